@@ -1,7 +1,22 @@
 #!/bin/bash
 
 # Define version
-VERSION="v1.0.0"
+VERSION=${VERSION:-v1.0.0}
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo "unknown")
+BUILD_DATE=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
+CHANNEL=${CHANNEL:-stable}
+
+# Optional ed25519 release signing key (PEM), used to sign the binaries for 'floom update'.
+# Its public key is embedded into the binaries, which then only accept signed updates.
+SIGNING_KEY=${SIGNING_KEY:-}
+PUBLIC_KEY=""
+if [[ -n $SIGNING_KEY ]]; then
+    PUBLIC_KEY=$(openssl pkey -in "$SIGNING_KEY" -pubout -outform DER | tail -c 32 | base64)
+fi
+
+# Optional URL of the release index the binaries update from. Without it, 'floom update'
+# needs --index-url or FLOOM_UPDATE_INDEX_URL.
+INDEX_URL=${INDEX_URL:-}
 
 # Define platforms
 platforms=("windows/amd64" "linux/amd64" "darwin/amd64" "darwin/arm64")
@@ -9,6 +24,9 @@ platforms=("windows/amd64" "linux/amd64" "darwin/amd64" "darwin/arm64")
 # Create the build directory if it doesn't exist
 mkdir -p build
 
+# Release index entry with the checksum and signature of each binary
+assets=""
+
 # Loop through all platforms
 for platform in "${platforms[@]}"; do
     IFS='/' read -ra ADDR <<< "$platform"
@@ -29,8 +47,23 @@ for platform in "${platforms[@]}"; do
     echo "Building for $GOOS/$GOARCH..."
 
     # Build the binary
-    GOOS=$GOOS GOARCH=$GOARCH go build -ldflags="-s -w -X 'main.Version=${VERSION}'" -o "$output_name" .
+    GOOS=$GOOS GOARCH=$GOARCH go build -ldflags="-s -w -X 'main.Version=${VERSION}' -X 'main.Commit=${COMMIT}' -X 'main.BuildDate=${BUILD_DATE}' -X 'main.UpdatePublicKey=${PUBLIC_KEY}' -X 'main.UpdateIndexURL=${INDEX_URL}'" -o "$output_name" .
+
+    # Sign the binary
+    if [[ -n $SIGNING_KEY ]]; then
+        checksum=$(sha256sum "$output_name" | cut -d' ' -f1)
+        signature=$(openssl pkeyutl -sign -inkey "$SIGNING_KEY" -rawin -in "$output_name" | base64 | tr -d '\n')
+        echo "$signature" > "${output_name}.sig"
+        [[ -n $assets ]] && assets+=","
+        assets+="\n        \"${platform}\": {\"url\": \"$(basename "$output_name")\", \"sha256\": \"${checksum}\", \"signature\": \"${signature}\"}"
+    fi
 
 done
 
+# Write the release index entry, to be added to the releases of the index
+if [[ -n $SIGNING_KEY ]]; then
+    printf '{\n    "version": "%s",\n    "channel": "%s",\n    "date": "%s",\n    "assets": {%b\n    }\n}\n' "$VERSION" "$CHANNEL" "$BUILD_DATE" "$assets" > build/release.json
+    echo "Signed the binaries, the release index entry is in build/release.json."
+fi
+
 echo "Compilation finished."
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"FloomCLI/floomapi"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Global network flags, they override the network settings of every deployment.
+var (
+	requestTimeout time.Duration
+	requestRetries int
+	traceHARPath   string
+)
+
+// httpTracer is shared by every API client of a run, so the verbose log and the HAR file
+// cover all requests. It is created on first use, once flags are parsed.
+var (
+	httpTracer     *floomapi.Tracer
+	httpTracerOnce sync.Once
+)
+
+func sharedTracer() *floomapi.Tracer {
+	httpTracerOnce.Do(func() {
+		if !verbose && traceHARPath == "" {
+			return
+		}
+		var log io.Writer
+		if verbose {
+			log = os.Stderr
+		}
+		httpTracer = floomapi.NewTracer(log, traceHARPath, buildInfo.Version)
+	})
+	return httpTracer
+}
+
+// The cassette of a run is set up from FLOOM_RECORD or FLOOM_REPLAY. Recording captures
+// every API interaction, replaying serves them from the file without any network.
+var (
+	httpCassette     *floomapi.Cassette
+	httpCassetteErr  error
+	httpCassetteOnce sync.Once
+)
+
+func sharedCassette() (*floomapi.Cassette, error) {
+	httpCassetteOnce.Do(func() {
+		recordPath, replayPath := os.Getenv("FLOOM_RECORD"), os.Getenv("FLOOM_REPLAY")
+		switch {
+		case recordPath != "" && replayPath != "":
+			httpCassetteErr = fmt.Errorf("FLOOM_RECORD and FLOOM_REPLAY cannot be used together")
+		case recordPath != "":
+			httpCassette = floomapi.NewRecorder(recordPath)
+		case replayPath != "":
+			httpCassette, httpCassetteErr = floomapi.LoadCassette(replayPath)
+		}
+	})
+	return httpCassette, httpCassetteErr
+}
+
+// newAPIClient builds the Floom API client for a deployment target. Tests replace it to
+// run commands against a fake implementation of floomapi.API.
+var newAPIClient = func(deploymentType string, credentials floomapi.Credentials) (floomapi.API, error) {
+	options, err := networkOptions(deploymentType)
+	if err != nil {
+		return nil, newExitError(exitConfig, "invalid network configuration for '%s': %w", deploymentType, err)
+	}
+
+	transport, err := transportFor(deploymentType)
+	if err != nil {
+		return nil, newExitError(exitConfig, "invalid network configuration for '%s': %w", deploymentType, err)
+	}
+	var roundTripper http.RoundTripper = transport
+	cassette, err := sharedCassette()
+	if err != nil {
+		return nil, newExitError(exitConfig, "%w", err)
+	}
+	if cassette != nil {
+		roundTripper = cassette.Wrap(roundTripper)
+	}
+	if tracer := sharedTracer(); tracer != nil {
+		roundTripper = tracer.Wrap(roundTripper)
+	}
+	options = append(options, floomapi.WithTransport(roundTripper))
+
+	if verbose {
+		options = append(options, floomapi.WithLogf(func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}))
+	}
+
+	return floomapi.NewClient(apiBaseURL(deploymentType), credentials, options...), nil
+}
+
+// apiBaseURL returns the URL of the API of a deployment target. FLOOM_ENDPOINT may point
+// the target at another server.
+func apiBaseURL(deploymentType string) string {
+	if endpoint := config.Endpoint(); endpoint != "" {
+		return endpoint
+	}
+	return floomapi.BaseURL(deploymentType)
+}
+
+// authenticatedClient returns an API client using the stored API key of the deployment.
+func authenticatedClient(deploymentType string) (floomapi.API, error) {
+	apiKey, err := config.GetApiKeyForDeployment(deploymentType)
+	if err != nil {
+		return nil, newExitError(exitAuth, "%w", err)
+	}
+
+	return newAPIClient(deploymentType, floomapi.Credentials{ApiKey: apiKey})
+}
+
+// insecureWarned holds the targets whose insecure_skip_verify warning was printed in this
+// run, as a deploy creates several clients for the same target.
+var insecureWarned sync.Map
+
+// transportFor creates the HTTP transport with the TLS and proxy settings of a deployment.
+func transportFor(deploymentType string) (*http.Transport, error) {
+	var settings floomapi.TransportSettings
+	if network := config.GetNetworkConfigForDeployment(deploymentType); network != nil {
+		settings.ProxyURL = network.ProxyURL
+		if network.TLS != nil {
+			settings.CAFile = network.TLS.CAFile
+			settings.CertFile = network.TLS.CertFile
+			settings.KeyFile = network.TLS.KeyFile
+			settings.InsecureSkipVerify = network.TLS.InsecureSkipVerify
+		}
+	}
+
+	if settings.InsecureSkipVerify {
+		if _, warned := insecureWarned.LoadOrStore(deploymentType, true); !warned {
+			color.New(color.FgHiRed, color.Bold).Fprintf(os.Stderr,
+				"WARNING: TLS certificate verification is disabled for '%s' (insecure_skip_verify).\n"+
+					"Anyone on the network path can intercept your API key and pipelines. Do not use this in production.\n",
+				deploymentType)
+		}
+	}
+
+	return floomapi.NewTransport(settings)
+}
+
+// networkOptions converts the network settings of a deployment and the global network
+// flags into client options. Flags take precedence over the configuration.
+func networkOptions(deploymentType string) ([]floomapi.Option, error) {
+	defaults := floomapi.DefaultRequestSettings
+	var options []floomapi.Option
+
+	if network := config.GetNetworkConfigForDeployment(deploymentType); network != nil {
+		var err error
+		if defaults, err = applyRequestConfig(defaults, network.RequestConfiguration); err != nil {
+			return nil, err
+		}
+
+		for operation, endpoint := range network.Endpoints {
+			settings, err := applyRequestConfig(defaults, endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("endpoint '%s': %w", operation, err)
+			}
+			options = append(options, floomapi.WithEndpointSettings(operation, applyNetworkFlags(settings)))
+		}
+	}
+
+	options = append([]floomapi.Option{floomapi.WithRequestSettings(applyNetworkFlags(defaults))}, options...)
+	return options, nil
+}
+
+func applyRequestConfig(settings floomapi.RequestSettings, request config.RequestConfiguration) (floomapi.RequestSettings, error) {
+	durations := []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"timeout", request.Timeout, &settings.Timeout},
+		{"initial_backoff", request.InitialBackoff, &settings.InitialBackoff},
+		{"max_backoff", request.MaxBackoff, &settings.MaxBackoff},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s: %w", duration.name, err)
+		}
+		*duration.field = parsed
+	}
+
+	if request.MaxRetries != nil {
+		settings.MaxRetries = *request.MaxRetries
+	}
+
+	return settings, nil
+}
+
+func applyNetworkFlags(settings floomapi.RequestSettings) floomapi.RequestSettings {
+	if rootCmd.PersistentFlags().Changed("timeout") {
+		settings.Timeout = requestTimeout
+	}
+	if rootCmd.PersistentFlags().Changed("retries") {
+		settings.MaxRetries = requestRetries
+	}
+	return settings
+}
+
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", floomapi.DefaultTimeout, "Timeout of a single API request attempt without progress, uploads may take longer while data flows")
+	rootCmd.PersistentFlags().StringVar(&traceHARPath, "trace-har", "", "Write all API requests and responses to a HAR file, with secrets redacted")
+	rootCmd.PersistentFlags().IntVar(&requestRetries, "retries", floomapi.DefaultRequestSettings.MaxRetries, "Number of retries for transient API failures")
+}
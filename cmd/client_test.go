@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInsecureWarningOnce(t *testing.T) {
+	profile := newProfile(t)
+	if _, code := runCommand(t, "--profile", profile, "config", "set", "deployments.selfhosted.network.tls.insecure_skip_verify", "true"); code != 0 {
+		t.Fatalf("config set exit code = %d", code)
+	}
+
+	insecureWarned.Delete("selfhosted")
+	stderr := os.Stderr
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = writer
+	for i := 0; i < 3; i++ {
+		if _, err := transportFor("selfhosted"); err != nil {
+			t.Errorf("transportFor failed: %v", err)
+		}
+	}
+	writer.Close()
+	os.Stderr = stderr
+
+	output, _ := io.ReadAll(reader)
+	if count := strings.Count(string(output), "WARNING"); count != 1 {
+		t.Errorf("printed the warning %d times, want once:\n%s", count, output)
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Reads and changes configuration settings",
+	Long: `Reads and changes the settings of config.json with dotted keys. Keys starting with
+'deployments' address the deployments of the active profile; segments containing dots,
+such as custom endpoint URLs, are written in brackets. API keys are always masked.
+
+Examples:
+  floom config get credential_store.backend
+  floom config set deployments.cloud.network.timeout 1m
+  floom config set deployments[https://floom.example.com].network.max_retries 5
+  floom config unset deployments.cloud.network
+  floom config list --output yaml
+  floom config edit`,
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Prints the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := config.GetValue(args[0])
+		if err != nil {
+			return newExitError(exitConfig, "%w", err)
+		}
+		return printValue(args[0], value)
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Changes a setting",
+	Long: `Changes a setting. The value is kept as a string if the setting takes one, otherwise it is
+parsed as JSON, e.g. 3, true or {"timeout": "30s"}. The configuration is validated before
+it is saved. API keys cannot be set, use 'floom login' instead.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.SetValue(args[0], args[1]); err != nil {
+			return newExitError(exitValidation, "failed to change setting: %w", err)
+		}
+		return nil
+	},
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Removes a setting, restoring its default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		found, err := config.UnsetValue(args[0])
+		if err != nil {
+			return newExitError(exitValidation, "failed to remove setting: %w", err)
+		}
+		if !found {
+			printNote("'%s' is not set.", args[0])
+		}
+		return nil
+	},
+}
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:   "list [key]",
+	Short: "Lists all settings, or the settings below a key",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := ""
+		if len(args) > 0 {
+			key = args[0]
+		}
+
+		value, err := config.GetValue(key)
+		if err != nil {
+			return newExitError(exitConfig, "%w", err)
+		}
+		return printValue(key, value)
+	},
+}
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Opens the configuration in $VISUAL or $EDITOR",
+	Long: `Opens a copy of the configuration in $VISUAL or $EDITOR. The edited configuration is
+validated before it replaces the current one; when it is invalid, it can be edited again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if config.ReadOnly() {
+			return newExitError(exitConfig, "the configuration is read-only")
+		}
+		if err := editConfig(); err != nil {
+			// An invalid configuration the user gave up on keeps its validation exit code
+			var exitErr *exitError
+			if errors.As(err, &exitErr) {
+				return err
+			}
+			return newExitError(exitConfig, "failed to edit configuration: %w", err)
+		}
+		return nil
+	},
+}
+
+// editConfig edits a temporary copy of the configuration until it is valid or the user
+// gives up, and saves it.
+func editConfig() error {
+	original, err := config.EditableConfig()
+	if err != nil {
+		return err
+	}
+
+	// The copy may hold plaintext API keys of older configurations, CreateTemp makes it
+	// readable by the user only
+	file, err := os.CreateTemp("", "floom-config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(original)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	for {
+		if err := runEditor(file.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("No changes.")
+			return nil
+		}
+
+		err = config.ReplaceConfig(edited)
+		if err == nil {
+			fmt.Println("Configuration saved.")
+			return nil
+		}
+
+		fmt.Fprintln(os.Stderr, "The configuration is invalid:", err)
+		fmt.Fprint(os.Stderr, "Edit again? [Y/n] ")
+		answer, _ := input.ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "" && answer != "y" && answer != "yes" {
+			return newExitError(exitValidation, "changes discarded, the configuration is invalid: %w", err)
+		}
+	}
+}
+
+// runEditor opens a file in the editor of $VISUAL or $EDITOR, which may include arguments.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	fields := strings.Fields(editor)
+	editorCmd := exec.Command(fields[0], append(fields[1:], path)...)
+	editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %w", editor, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEditCmd)
+}
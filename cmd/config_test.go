@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	// The steps share the configuration and run in order
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{name: "set", args: []string{"config", "set", "deployments.cloud.network.timeout", "1m"}},
+		{name: "get", args: []string{"config", "get", "deployments.cloud.network.timeout"}, want: "1m\n"},
+		{name: "set JSON", args: []string{"config", "set", "deployments.cloud.network.max_retries", "5"}},
+		{name: "list", args: []string{"config", "list", "deployments.cloud.network"}, want: "deployments.cloud.network.max_retries = 5\ndeployments.cloud.network.timeout = 1m\n"},
+		{name: "set invalid duration", args: []string{"config", "set", "deployments.cloud.network.timeout", "soon"}, wantCode: exitValidation},
+		{name: "set API key", args: []string{"config", "set", "deployments.cloud.credentials.api_key", "secret"}, wantCode: exitValidation},
+		{name: "unset", args: []string{"config", "unset", "deployments.cloud.network.timeout"}},
+		{name: "get unset", args: []string{"config", "get", "deployments.cloud.network.timeout"}, wantCode: exitConfig},
+		{name: "usage", args: []string{"config", "set", "credential_store.backend"}, wantCode: exitUsage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, code := runCommand(t, test.args...)
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if test.want != "" && output != test.want {
+				t.Errorf("output = %q, want %q", output, test.want)
+			}
+		})
+	}
+
+	// The last step removes the deployment's remaining settings
+	if _, code := runCommand(t, "config", "unset", "deployments.cloud"); code != 0 {
+		t.Fatalf("unset exit code = %d", code)
+	}
+	if output, _ := runCommand(t, "config", "list", "--output", "json"); strings.Contains(output, "max_retries") {
+		t.Errorf("config list still holds the removed settings:\n%s", output)
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test editor is a shell script")
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		answers  string
+		wantCode int
+		want     string
+	}{
+		{name: "unchanged", script: "true", want: "No changes.\n"},
+		{name: "invalid, discarded", script: `echo '{"schema_version": "one"}' > "$1"`, answers: "n\n", wantCode: exitValidation},
+		{name: "invalid twice, discarded", script: `echo '{"profiles": []}' > "$1"`, answers: "y\nn\n", wantCode: exitValidation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := filepath.Join(t.TempDir(), "editor.sh")
+			if err := os.WriteFile(editor, []byte("#!/bin/sh\n"+test.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("VISUAL", editor)
+
+			stdin := os.Stdin
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			writer.WriteString(test.answers)
+			writer.Close()
+			os.Stdin = reader
+			defer func() { os.Stdin = stdin }()
+
+			// Errors and the prompt go to stderr
+			output, code := runCommand(t, "config", "edit")
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if output != test.want {
+				t.Errorf("output = %q, want %q", output, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"fmt"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"os"
+	"sort"
+)
+
+// credentialsCmd represents the credentials command
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manages where API keys are stored",
+	Long: `API keys are kept out of config.json, which only holds a reference to each key. The
+backend is selected with "credential_store" in config.json:
+
+  "file"       A passphrase-encrypted file next to config.json (default). The passphrase
+               is asked for once per run, or read from FLOOM_CREDENTIALS_PASSPHRASE.
+               Outside a terminal, e.g. in CI, FLOOM_CREDENTIALS_PASSPHRASE must be set,
+               also on the first run that creates the file.
+  "helper"     An external credential helper, e.g. a wrapper around your secret manager:
+               {"credential_store": {"backend": "helper", "helper": "my-floom-helper"}}
+               Arguments with spaces are quoted with single or double quotes.
+               The helper is run as '<helper> get|store|erase' with a JSON request
+               {"ref": "...", "secret": "..."} on stdin and prints {"secret": "..."} for get.
+  "plaintext"  config.json itself, as older versions did. Not recommended.
+
+Configurations of older versions keep their keys in config.json until they are migrated
+with 'floom credentials migrate'.`,
+}
+
+// credentialsStatusCmd represents the credentials status command
+var credentialsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the credential backend and where each API key is stored",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result := credentialsStatus{
+			Backend: config.GetConfig().CredentialBackend(),
+			Profile: config.ActiveProfileName(),
+			Keys:    []storedKey{},
+		}
+
+		var deployments []string
+		deploymentConfigs := config.ActiveProfile().Deployments
+		for deploymentType, deployment := range deploymentConfigs {
+			if deployment.Credentials.HasApiKey() {
+				deployments = append(deployments, deploymentType)
+			}
+		}
+		sort.Strings(deployments)
+
+		for _, deploymentType := range deployments {
+			credentials := deploymentConfigs[deploymentType].Credentials
+			location := "config.json (plaintext)"
+			if credentials.ApiKeyRef != "" {
+				location = credentials.ApiKeyRef
+			}
+			result.Keys = append(result.Keys, storedKey{Target: deploymentType, Location: location})
+		}
+
+		if err := printResult(result); err != nil {
+			return err
+		}
+		if len(config.PlaintextApiKeys()) > 0 && result.Backend != config.BackendPlaintext {
+			printNote("Some API keys are stored in plaintext. Run 'floom credentials migrate' to move them to the credential store.")
+		}
+		return nil
+	},
+}
+
+// credentialsStatus is the output of 'floom credentials status'.
+type credentialsStatus struct {
+	Backend string      `json:"backend" yaml:"backend"`
+	Profile string      `json:"profile" yaml:"profile"`
+	Keys    []storedKey `json:"keys" yaml:"keys"`
+}
+
+// storedKey is where the API key of a deployment is stored.
+type storedKey struct {
+	Target   string `json:"target" yaml:"target"`
+	Location string `json:"location" yaml:"location"`
+}
+
+func (status credentialsStatus) printText() {
+	fmt.Println("Credential backend:", status.Backend)
+	fmt.Println("Profile:", status.Profile)
+	for _, key := range status.Keys {
+		fmt.Printf("  %s: %s\n", key.Target, key.Location)
+	}
+}
+
+func (status credentialsStatus) tableRows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(status.Keys))
+	for _, key := range status.Keys {
+		rows = append(rows, []string{key.Target, key.Location})
+	}
+	return []string{"target", "location"}, rows
+}
+
+// credentialsMigrateCmd represents the credentials migrate command
+var credentialsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Moves all API keys to the configured credential backend",
+	Long: `Moves every API key to the configured credential backend: plaintext keys of config.json,
+written by older versions, as well as keys of a previously configured backend.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrated, err := config.MigrateCredentials()
+		if migrated == nil {
+			migrated = []string{}
+		}
+
+		// Keys migrated before a failure are reported too
+		if len(migrated) > 0 || err == nil {
+			if printErr := printResult(credentialsMigration{Migrated: migrated}); printErr != nil {
+				return printErr
+			}
+		}
+		switch {
+		case err != nil && len(migrated) > 0:
+			return newExitError(exitPartial, "failed to migrate all credentials: %w", err)
+		case err != nil:
+			return newExitError(exitConfig, "failed to migrate credentials: %w", err)
+		}
+		return nil
+	},
+}
+
+// credentialsMigration is the output of 'floom credentials migrate': the deployments whose
+// API keys were moved.
+type credentialsMigration struct {
+	Migrated []string `json:"migrated" yaml:"migrated"`
+}
+
+func (migration credentialsMigration) printText() {
+	for _, deploymentType := range migration.Migrated {
+		fmt.Printf("Migrated the API key of '%s'.\n", deploymentType)
+	}
+	if len(migration.Migrated) == 0 {
+		fmt.Println("All API keys are already stored in the configured credential backend.")
+	}
+}
+
+// promptPassphrase asks for the passphrase of the encrypted credential file on the
+// terminal, twice when a new file is created.
+func promptPassphrase(confirm bool) (string, error) {
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return "", fmt.Errorf("the credential store is locked, set FLOOM_CREDENTIALS_PASSPHRASE when not running in a terminal")
+	}
+
+	if confirm {
+		fmt.Fprintln(os.Stderr, "Choose a passphrase to encrypt your Floom API keys.")
+	}
+	fmt.Fprint(os.Stderr, "Credential store passphrase: ")
+	passphrase, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return string(passphrase), err
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	repeated, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(repeated) != string(passphrase) {
+		return "", fmt.Errorf("the passphrases do not match")
+	}
+	return string(passphrase), nil
+}
+
+func init() {
+	config.PassphrasePrompt = promptPassphrase
+
+	rootCmd.AddCommand(credentialsCmd)
+	credentialsCmd.AddCommand(credentialsStatusCmd)
+	credentialsCmd.AddCommand(credentialsMigrateCmd)
+}
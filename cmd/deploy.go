@@ -2,17 +2,21 @@ package cmd
 
 import (
 	"FloomCLI/config"
+	"FloomCLI/floomapi"
 	"FloomCLI/utils"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // deployCmd represents the deployment command
 var deployCmd = &cobra.Command{
-	Use:   "deploy [local|cloud|custom_endpoint] [file]",
+	Use:   "deploy [local|cloud|custom_endpoint] [file|-]",
 	Short: "Deploy pipeline configurations to Floom",
 	Long: `Deploy pipeline configurations to a local Floom Docker instance or to the Floom cloud.
 
@@ -23,29 +27,56 @@ For cloud deployment, use:
     floom deploy cloud pipeline.yml
 
 For custom endpoint deployment, use:
-	floom deploy http://184.152.3.12 pipeline.yml`,
+	floom deploy http://184.152.3.12 pipeline.yml
 
-	Args: cobra.MinimumNArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		deploymentType := args[0]
-		yamlFile := args[1]
+The prompt template can be kept in its own file with 'file: prompts/support.md' in
+prompt.template. Its placeholders are checked and its content is inlined at deploy time,
+or uploaded as an asset with --upload-templates.
 
-		// validate deploymentType is non empty and yamlFile are valid
+To read pipelines from standard input, pass '-' as the file. Files and streams may contain
+several '---' separated documents; each document is deployed as its own pipeline:
+    generate-pipelines | floom deploy local - --base-dir ./pipelines
+
+A pipeline can extend another pipeline with 'extends: ./base.yml'. If a target overlay
+such as pipeline.cloud.yml exists next to pipeline.yml, it is merged on top when deploying
+to that target. To print the merged pipeline without deploying it, use:
+    floom deploy cloud pipeline.yml --dry-run
+
+The target can be left out when FLOOM_TARGET or a .floom.json project file sets it:
+    FLOOM_TARGET=cloud floom deploy pipeline.yml`,
+
+	Args: deployArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// A single argument is the file, deployed to the default target
+		deploymentType, yamlFile := config.DefaultTarget(), args[0]
+		if len(args) > 1 {
+			deploymentType, yamlFile = args[0], args[1]
+		}
 
 		if deploymentType == "" {
-			fmt.Println("Deployment type is required, use 'local' or 'cloud'.")
-			return
+			return newExitError(exitUsage, "deployment type is required, use 'local' or 'cloud'")
 		}
 
 		if yamlFile == "" {
-			fmt.Println("YAML file is required")
-			return
+			return newExitError(exitUsage, "YAML file is required")
+		}
+
+		if deployUploadConcurrency < 1 {
+			return newExitError(exitUsage, "upload concurrency must be at least 1")
 		}
 
-		deploy(deploymentType, yamlFile)
+		return deploy(cmd.Context(), deploymentType, yamlFile)
 	},
 }
 
+// deployArgs accepts a target and a file, or only a file when a default target is set.
+func deployArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 && config.DefaultTarget() != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(2)(cmd, args)
+}
+
 func resolveYamlPath(yamlFile string, cwd ...string) (string, error) {
 	if filepath.IsAbs(yamlFile) {
 		return yamlFile, nil
@@ -75,117 +106,436 @@ func resolveYamlPath(yamlFile string, cwd ...string) (string, error) {
 	return filepath.Join(workingDir, yamlFile), nil
 }
 
-func deploy(deploymentType string, yamlFile string) {
-	// Implementation for deploying to local Floom Docker instance
-	appConfig := config.GetConfig()
+// defaultUploadConcurrency is the number of context files uploaded in parallel by default.
+const defaultUploadConcurrency = 4
 
-	// Check for cloud deployment configuration; initialize if not found
-	if deploymentType == "cloud" && !config.DeploymentConfigExists(deploymentType) {
-		fmt.Println("Cloud deployment configuration not found. Initializing...")
-		initializeConfigForDeployment(deploymentType)
+var (
+	deployDryRun            bool
+	deployBaseDir           string
+	deployUploadTemplates   bool
+	deployUploadConcurrency int
+)
+
+// deployResult is the output of 'floom deploy': the pipelines deployed to a target.
+type deployResult struct {
+	Target    string             `json:"target" yaml:"target"`
+	Pipelines []deployedPipeline `json:"pipelines" yaml:"pipelines"`
+}
+
+// deployedPipeline is a deployed pipeline and the asset IDs of its uploaded files. Only
+// cloud pipelines of registered users have a URL.
+type deployedPipeline struct {
+	Name            string   `json:"name" yaml:"name"`
+	URL             string   `json:"url,omitempty" yaml:"url,omitempty"`
+	AssetIDs        []string `json:"asset_ids,omitempty" yaml:"asset_ids,omitempty"`
+	TemplateAssetID string   `json:"template_asset_id,omitempty" yaml:"template_asset_id,omitempty"`
+
+	// apiKey is shown in the usage instructions of the text output only
+	apiKey string
+}
+
+func (result deployResult) printText() {
+	for _, pipeline := range result.Pipelines {
+		pipeline.printText()
 	}
+}
 
-	yamlPath, err := resolveYamlPath(yamlFile)
-	if err != nil {
-		fmt.Println("Error resolving YAML path:", err)
+func (pipeline deployedPipeline) printText() {
+	fmt.Printf("Pipeline '%s' deployed successfully.\n", pipeline.Name)
+	if pipeline.URL == "" {
 		return
 	}
+	fmt.Println("Pipeline URL:", pipeline.URL)
+	fmt.Println("You can send an HTTP POST request to this URL with the following headers:")
+	fmt.Println("API-Key:", pipeline.apiKey)
+	fmt.Println("Content-Type: application/json")
+	fmt.Println("In the request body, include a JSON with a 'prompt' field, for example:")
+	fmt.Println(`{"prompt": "Your prompt example here"}`)
+}
+
+func (result deployResult) tableRows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(result.Pipelines))
+	for _, pipeline := range result.Pipelines {
+		rows = append(rows, []string{pipeline.Name, result.Target, pipeline.URL, strings.Join(pipeline.AssetIDs, ",")})
+	}
+	return []string{"pipeline", "target", "url", "asset ids"}, rows
+}
 
-	// 1. Parse YAML to find context files
-	FloomYaml, err := utils.ParseYaml(yamlPath)
+// deploy deploys the pipelines of a file. If some of several pipelines were deployed
+// before one failed, they are reported and the error is a partial failure.
+func deploy(ctx context.Context, deploymentType string, yamlFile string) error {
+	// 1. Parse YAML, resolve 'extends' and the target overlay of every document
+	documents, err := loadPipelineDocuments(deploymentType, yamlFile)
 	if err != nil {
-		fmt.Println("Error parsing Floom YAML file:", err)
-		return
+		return newExitError(exitValidation, "failed to parse Floom YAML file: %w", err)
 	}
 
-	// 2. Upload context files and get asset IDs
+	if deployDryRun {
+		return printMergedPipelines(documents)
+	}
+
+	// Check for cloud deployment configuration; initialize if not found. A key given with
+	// FLOOM_API_KEY needs no registration.
+	if deploymentType == "cloud" && !config.DeploymentConfigExists(deploymentType) && os.Getenv(config.ApiKeyEnvVar) == "" {
+		printNote("Cloud deployment configuration not found. Initializing...")
+		registration, err := initializeConfigForDeployment(ctx, deploymentType)
+		if err != nil {
+			return err
+		}
+		if registration.Registered {
+			printNote("Registered new user '%s'.", registration.Username)
+		}
+	}
 
-	// implement FloomYaml.Prompt.Context iteration
-	for _, context := range FloomYaml.Pipeline.Prompt.Context {
-		pathInterface := context.Configuration["path"]
+	client, err := authenticatedClient(deploymentType)
+	if err != nil {
+		return err
+	}
+
+	// A single progress view covers the uploads of all documents
+	progress := utils.NewProgressGroup()
 
-		// Check if pathInterface is a string (single path)
-		if path, ok := pathInterface.(string); ok {
-			fileId, err := utils.UploadFile(deploymentType, path)
+	// Each document is deployed as its own pipeline, stopping at the first failure. The
+	// pipelines deployed before it are still reported: as text right away, in the other
+	// formats as one result at the end.
+	result := deployResult{Target: deploymentType}
+	var deployErr error
+	for _, document := range documents {
+		pipeline, err := deployPipelineDocument(ctx, client, deploymentType, document, progress)
+		if err != nil {
+			deployErr = err
+			break
+		}
+		result.Pipelines = append(result.Pipelines, *pipeline)
+		if outputFormat == outputText {
+			pipeline.printText()
+		}
+	}
+
+	if len(result.Pipelines) > 0 && outputFormat != outputText {
+		if err := printResult(result); err != nil {
+			return err
+		}
+	}
+	if deployErr != nil && len(result.Pipelines) > 0 {
+		return newExitError(exitPartial, "deployed %d of %d pipelines: %w", len(result.Pipelines), len(documents), deployErr)
+	}
+	return deployErr
+}
+
+// loadPipelineDocuments reads the pipelines to deploy from a file, or from stdin when
+// yamlFile is "-". Relative references resolve against --base-dir, or the working directory.
+func loadPipelineDocuments(deploymentType, yamlFile string) ([]*utils.PipelineDocument, error) {
+	if yamlFile == "-" {
+		baseDir := deployBaseDir
+		if baseDir == "" {
+			dir, err := os.Getwd()
 			if err != nil {
-				fmt.Println("Error uploading the file:", err)
-				return
-			}
-			// Replace 'path' with 'assetId' (array of one element)
-			context.Configuration["assetId"] = []string{fileId}
-		} else if paths, ok := pathInterface.([]interface{}); ok {
-			// Handle case where path is an array of strings
-			var fileIds []string
-			for _, pathElement := range paths {
-				path, ok := pathElement.(string)
-				if !ok {
-					fmt.Println("Error: path is not a string in the array")
-					return
-				}
-				fileId, err := utils.UploadFile(deploymentType, path)
-				if err != nil {
-					fmt.Println("Error uploading the file:", err)
-					return
-				}
-				fileIds = append(fileIds, fileId)
+				return nil, err
 			}
-			// Replace 'path' with 'assetId' (array of file IDs)
-			context.Configuration["assetId"] = fileIds
+			baseDir = dir
 		}
-		// Remove the original 'path' entry
-		delete(context.Configuration, "path")
+		return utils.LoadPipelineStream(os.Stdin, yamlFile, baseDir)
+	}
+
+	yamlPath, err := resolveYamlPath(yamlFile)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving YAML path: %w", err)
+	}
+
+	return utils.LoadPipelineFile(yamlPath, deployBaseDir, deploymentType)
+}
+
+// deployPipelineDocument uploads the context files of a single pipeline document and
+// commits it.
+func deployPipelineDocument(ctx context.Context, client floomapi.API, deploymentType string, document *utils.PipelineDocument, progress *utils.ProgressGroup) (*deployedPipeline, error) {
+	appConfig := config.GetConfig()
+	FloomYaml := document.Pipeline
+
+	// Fields unknown to this CLI version are kept and committed unchanged
+	if unknownFields := FloomYaml.UnknownFields(); verbose && len(unknownFields) > 0 {
+		printNote("Note: passing through fields not known to this CLI version: %s", strings.Join(unknownFields, ", "))
+	}
+
+	// Inline or upload the prompt template file, if the template references one
+	templateAssetId, err := resolvePromptTemplate(ctx, client, document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve prompt template of '%s': %w", FloomYaml.Pipeline.Name, err)
+	}
+
+	// 2. Upload context files and get asset IDs
+	assetIds, err := uploadContextFiles(ctx, client, document, progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload the files of '%s': %w", FloomYaml.Pipeline.Name, err)
 	}
 
 	// 3. Replace context paths with asset IDs in the YAML
 	// 4. Commit the modified pipeline configuration
-	err = utils.DeployPipeline(deploymentType, *FloomYaml)
+	err = client.CommitPipeline(ctx, *FloomYaml)
 	if err != nil {
-		fmt.Println("Error deploying pipeline:", err)
-		return
+		return nil, commitError(document, err)
 	}
 
 	// Fetch the API key and username for the deployment
 	apiKey, err := config.GetApiKeyForDeployment(deploymentType)
 	if err != nil {
-		fmt.Println("Error fetching API key for deployment:", err)
-		return
+		return nil, newExitError(exitAuth, "failed to fetch API key for deployment: %w", err)
 	}
 
-	// Assuming GetConfig() and Username retrieval based on updated config structure
-	deploymentConfig, exists := appConfig.Deployments[deploymentType]
-	if deploymentType != "local" && !exists {
-		fmt.Println("Deployment type not found in configuration")
-		return
+	// Keys given with FLOOM_API_KEY work without a stored deployment, but have no username
+	deploymentConfig, exists := config.ActiveProfile().Deployments[deploymentType]
+	if deploymentType != "local" && !exists && os.Getenv(config.ApiKeyEnvVar) == "" {
+		return nil, newExitError(exitConfig, "deployment type '%s' not found in configuration", deploymentType)
 	}
 	username := deploymentConfig.Credentials.Username
 
-	// Construct the pipeline URL
-	pipelineURL := fmt.Sprintf("https://%s-%s.pipeline.floom.ai/", FloomYaml.Pipeline.Name, username)
+	pipeline := &deployedPipeline{
+		Name:            FloomYaml.Pipeline.Name,
+		AssetIDs:        assetIds,
+		TemplateAssetID: templateAssetId,
+		apiKey:          apiKey,
+	}
+
+	// Cloud pipelines are reached through their URL, which instructions are printed for
+	if deploymentType == "cloud" && username != "" {
+		pipeline.URL = fmt.Sprintf("https://%s-%s.pipeline.floom.ai/", FloomYaml.Pipeline.Name, username)
 
-	// Print success message, pipeline URL, and instructions for making HTTP POST request
-	fmt.Printf("Pipeline '%s' deployed successfully.\n", FloomYaml.Pipeline.Name)
+		// Cloud deployments are reached through their URL, so no port is stored
+		var port *int
 
-	if deploymentType == "cloud" {
-		fmt.Println("Pipeline URL:", pipelineURL)
-		fmt.Println("You can send an HTTP POST request to this URL with the following headers:")
-		fmt.Println("API-Key:", apiKey)
-		fmt.Println("Content-Type: application/json")
-		fmt.Println("In the request body, include a JSON with a 'prompt' field, for example:")
-		fmt.Println(`{"prompt": "Your prompt example here"}`)
+		// Add the pipeline to the configuration
+		if err := appConfig.AddOrUpdatePipeline(deploymentType, FloomYaml.Pipeline.Name, pipeline.URL, port); err != nil {
+			return nil, newExitError(exitConfig, "pipeline '%s' was deployed, but saving it to the configuration failed: %w", FloomYaml.Pipeline.Name, err)
+		}
+	}
+
+	return pipeline, nil
+}
 
-		// Construct the pipeline URL for cloud deployments
-		var port *int // Set to nil by default, indicating cloud deployment or irrelevant port
-		if deploymentType != "cloud" {
-			// For non-cloud deployments, you might have a specific port to use
-			// Example: port = new(int); *port = 8080
+// contextFiles are the files of one prompt context plugin and the asset IDs they get.
+type contextFiles struct {
+	configuration map[string]interface{}
+	paths         []string
+	assetIds      []string
+}
+
+// contextUpload is a single context file upload and the slot its asset ID is stored in.
+type contextUpload struct {
+	files *contextFiles
+	index int
+	path  string
+	bar   *utils.ProgressBar
+}
+
+// runContextUploads uploads files with at most concurrency uploads in flight. Every asset
+// ID is stored at the position of its path, so the declared order is kept. The first
+// failure cancels the uploads in flight and skips the remaining ones.
+func runContextUploads(ctx context.Context, client floomapi.API, jobs []contextUpload, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	queue := make(chan contextUpload)
+
+	for worker := 0; worker < concurrency && worker < len(jobs); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				fileId, err := client.UploadAsset(uploadCtx, job.path, job.bar.Update)
+				if err != nil {
+					failOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				job.bar.Done()
+				job.files.assetIds[job.index] = fileId
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-uploadCtx.Done():
+			break feed
 		}
+	}
+	close(queue)
+	wg.Wait()
 
-		// Add the pipeline to the configuration
-		appConfig.AddOrUpdatePipeline(deploymentType, FloomYaml.Pipeline.Name, pipelineURL, port)
+	if firstErr == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// uploadContextFiles uploads the files of every prompt context and replaces each 'path'
+// entry with an 'assetId' list holding the asset IDs in the declared path order. It returns
+// the asset IDs of all contexts.
+func uploadContextFiles(ctx context.Context, client floomapi.API, document *utils.PipelineDocument, progress *utils.ProgressGroup) ([]string, error) {
+	prompt := document.Pipeline.Pipeline.Prompt
+	if prompt == nil {
+		return nil, nil
+	}
+
+	var contexts []*contextFiles
+	for _, context := range prompt.Context {
+		files := &contextFiles{configuration: context.Configuration}
+
+		// A path is either a single string or an array of strings
+		switch pathInterface := context.Configuration["path"].(type) {
+		case string:
+			files.paths = []string{document.ResolvePath(pathInterface)}
+		case []interface{}:
+			for _, pathElement := range pathInterface {
+				path, ok := pathElement.(string)
+				if !ok {
+					return nil, fmt.Errorf("path is not a string in the array")
+				}
+				files.paths = append(files.paths, document.ResolvePath(path))
+			}
+		default:
+			continue
+		}
+
+		files.assetIds = make([]string, len(files.paths))
+		contexts = append(contexts, files)
+	}
+
+	// Register every file up front so the progress total covers the whole pipeline
+	var jobs []contextUpload
+	for _, files := range contexts {
+		for i, path := range files.paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, newExitError(exitValidation, "error opening file: %w", err)
+			}
+			bar := progress.Add(filepath.Base(path), info.Size())
+			jobs = append(jobs, contextUpload{files: files, index: i, path: path, bar: bar})
+		}
+	}
+
+	if err := runContextUploads(ctx, client, jobs, deployUploadConcurrency); err != nil {
+		progress.Finish()
+		return nil, err
+	}
+	if len(jobs) > 0 {
+		progress.Finish()
+	}
+
+	// Replace 'path' with 'assetId' (array of file IDs)
+	var assetIds []string
+	for _, files := range contexts {
+		files.configuration["assetId"] = files.assetIds
+		delete(files.configuration, "path")
+		assetIds = append(assetIds, files.assetIds...)
+	}
+
+	return assetIds, nil
+}
+
+// resolvePromptTemplate replaces a 'file' reference in prompt.template with the checked
+// content of the file, or with the asset ID of the uploaded file if --upload-templates is set.
+// It returns the asset ID of an uploaded file.
+func resolvePromptTemplate(ctx context.Context, client floomapi.API, document *utils.PipelineDocument) (string, error) {
+	prompt := document.Pipeline.Pipeline.Prompt
+	if prompt == nil || prompt.Template == nil {
+		return "", nil
+	}
+	configuration := prompt.Template.Configuration
+	if _, exists := configuration[utils.TemplateFileKey]; !exists {
+		return "", nil
+	}
+
+	content, _, err := utils.TemplateContent(document)
+	if err != nil {
+		return "", newExitError(exitValidation, "%w", err)
+	}
+
+	fileId := ""
+	if deployUploadTemplates {
+		path := document.ResolvePath(configuration[utils.TemplateFileKey].(string))
+		fileId, err = client.UploadAsset(ctx, path, nil)
+		if err != nil {
+			return "", fmt.Errorf("error uploading the template file: %w", err)
+		}
+		configuration["assetId"] = []string{fileId}
+	} else {
+		configuration[utils.TemplateSystemKey] = content
+	}
+
+	// Remove the original 'file' entry
+	delete(configuration, utils.TemplateFileKey)
+	return fileId, nil
+}
+
+// commitError describes a failed commit. Field-level validation errors are listed one per
+// line with the location of the field in the pipeline YAML.
+func commitError(document *utils.PipelineDocument, err error) error {
+	var apiErr *floomapi.Error
+	if !errors.As(err, &apiErr) || len(apiErr.Details) == 0 {
+		return fmt.Errorf("failed to deploy pipeline: %w", err)
+	}
+
+	var message strings.Builder
+	message.WriteString("Validation errors:")
+	for _, detail := range apiErr.Details {
+		if detail.Field == "" {
+			fmt.Fprintf(&message, "\n  %s", detail.Message)
+			continue
+		}
+
+		node, _ := utils.LocateField(document.Node, detail.Field)
+		fmt.Fprintf(&message, "\n  %s:%d:%d: %s: %s", document.Source, node.Line, node.Column, detail.Field, detail.Message)
+	}
+	return newExitError(exitValidation, "failed to deploy pipeline: %s\n%s", apiErr.Summary(), message.String())
+}
+
+// printMergedPipelines prints the pipelines as they would be deployed to the target,
+// after resolving 'extends' and merging the target overlay. They are printed as YAML
+// documents, keeping comments, or as a JSON list with --output json.
+func printMergedPipelines(documents []*utils.PipelineDocument) error {
+	if outputFormat == outputJSON {
+		pipelines := make([]interface{}, 0, len(documents))
+		for _, document := range documents {
+			var pipeline interface{}
+			if err := document.Node.Decode(&pipeline); err != nil {
+				return fmt.Errorf("failed to serialize merged pipeline: %w", err)
+			}
+			pipelines = append(pipelines, pipeline)
+		}
+		return printResult(pipelines)
+	}
+
+	for i, document := range documents {
+		out, err := utils.SerializeYamlNode(document.Node)
+		if err != nil {
+			return fmt.Errorf("failed to serialize merged pipeline: %w", err)
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(out)
 	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(deployCmd)
-	// Here you can define flags and configuration settings.
+	deployCmd.Flags().BoolVar(&deployDryRun, "dry-run", false, "Print the merged pipeline without uploading or deploying anything")
+	deployCmd.Flags().IntVar(&deployUploadConcurrency, "upload-concurrency", defaultUploadConcurrency, "Number of context files uploaded in parallel")
+	deployCmd.Flags().BoolVar(&deployUploadTemplates, "upload-templates", false, "Upload prompt template files as assets instead of inlining their content")
+	deployCmd.Flags().StringVar(&deployBaseDir, "base-dir", "", "Directory relative file references are resolved against (default: the current directory)")
 }
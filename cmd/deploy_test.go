@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"FloomCLI/floomapi"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeploy(t *testing.T) {
+	validationErr := &floomapi.Error{
+		Operation:  "pipeline commit",
+		StatusCode: http.StatusBadRequest,
+		Status:     "400 Bad Request",
+		Category:   floomapi.CategoryValidation,
+		Details:    []floomapi.ValidationDetail{{Field: "pipeline.model[0].model", Message: "unknown model"}},
+	}
+	serverErr := &floomapi.Error{Operation: "pipeline commit", StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Category: floomapi.CategoryServer}
+
+	tests := []struct {
+		name         string
+		files        map[string]string
+		args         []string
+		commitErrors map[string]error
+		wantCode     int
+		wantCommits  []string
+		wantUploads  []string
+	}{
+		{
+			name:        "single pipeline",
+			files:       map[string]string{"pipeline.yml": pipelineYaml("docs")},
+			args:        []string{"deploy", "local", "pipeline.yml"},
+			wantCommits: []string{"docs"},
+		},
+		{
+			name:        "every document is a pipeline",
+			files:       map[string]string{"pipeline.yml": pipelineYaml("a") + "---\n" + pipelineYaml("b")},
+			args:        []string{"deploy", "local", "pipeline.yml"},
+			wantCommits: []string{"a", "b"},
+		},
+		{
+			name: "context files are uploaded in order",
+			files: map[string]string{
+				"pipeline.yml": pipelineYaml("docs") + "  prompt:\n    context:\n      - package: floom/prompt/context/pdf\n        path: [one.pdf, two.pdf]\n",
+				"one.pdf":      "one",
+				"two.pdf":      "two",
+			},
+			args:        []string{"deploy", "local", "pipeline.yml", "--upload-concurrency", "1"},
+			wantCommits: []string{"docs"},
+			wantUploads: []string{"one.pdf", "two.pdf"},
+		},
+		{
+			name:     "dry run deploys nothing",
+			files:    map[string]string{"pipeline.yml": pipelineYaml("docs")},
+			args:     []string{"deploy", "local", "pipeline.yml", "--dry-run"},
+			wantCode: 0,
+		},
+		{
+			name:         "rejected pipeline",
+			files:        map[string]string{"pipeline.yml": pipelineYaml("docs")},
+			args:         []string{"deploy", "local", "pipeline.yml"},
+			commitErrors: map[string]error{"docs": validationErr},
+			wantCode:     exitValidation,
+		},
+		{
+			name:         "server failure",
+			files:        map[string]string{"pipeline.yml": pipelineYaml("docs")},
+			args:         []string{"deploy", "local", "pipeline.yml"},
+			commitErrors: map[string]error{"docs": serverErr},
+			wantCode:     exitNetwork,
+		},
+		{
+			name:         "partial failure",
+			files:        map[string]string{"pipeline.yml": pipelineYaml("a") + "---\n" + pipelineYaml("b")},
+			args:         []string{"deploy", "local", "pipeline.yml"},
+			commitErrors: map[string]error{"b": serverErr},
+			wantCode:     exitPartial,
+			wantCommits:  []string{"a"},
+		},
+		{
+			name:     "missing context file",
+			files:    map[string]string{"pipeline.yml": pipelineYaml("docs") + "  prompt:\n    context:\n      - package: floom/prompt/context/pdf\n        path: missing.pdf\n"},
+			args:     []string{"deploy", "local", "pipeline.yml"},
+			wantCode: exitValidation,
+		},
+		{
+			name:     "invalid YAML",
+			files:    map[string]string{"pipeline.yml": "pipeline: [unclosed"},
+			args:     []string{"deploy", "local", "pipeline.yml"},
+			wantCode: exitValidation,
+		},
+		{
+			name:     "missing file argument",
+			args:     []string{"deploy", "local"},
+			wantCode: exitUsage,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			chdir(t, dir)
+
+			api := &fakeAPI{commitErrors: test.commitErrors}
+			useFakeAPI(t, api)
+
+			if _, code := runCommand(t, test.args...); code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+
+			var commits []string
+			for _, pipeline := range api.commits {
+				commits = append(commits, pipeline.Pipeline.Name)
+			}
+			if !reflect.DeepEqual(commits, test.wantCommits) {
+				t.Errorf("committed %v, want %v", commits, test.wantCommits)
+			}
+			var uploads []string
+			for _, upload := range api.uploads {
+				uploads = append(uploads, filepath.Base(upload))
+			}
+			if !reflect.DeepEqual(uploads, test.wantUploads) {
+				t.Errorf("uploaded %v, want %v", uploads, test.wantUploads)
+			}
+		})
+	}
+}
+
+func TestDeployReplacesPathsWithAssetIDs(t *testing.T) {
+	dir := t.TempDir()
+	pipeline := pipelineYaml("docs") + "  prompt:\n    context:\n      - package: floom/prompt/context/pdf\n        path: manual.pdf\n"
+	if err := os.WriteFile(filepath.Join(dir, "pipeline.yml"), []byte(pipeline), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manual.pdf"), []byte("%PDF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+
+	api := &fakeAPI{}
+	useFakeAPI(t, api)
+
+	output, code := runCommand(t, "deploy", "local", "pipeline.yml", "--output", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	var result deployResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	want := []string{"asset-1-manual.pdf"}
+	if len(result.Pipelines) != 1 || !reflect.DeepEqual(result.Pipelines[0].AssetIDs, want) {
+		t.Errorf("result = %+v, want the asset IDs %v", result, want)
+	}
+
+	context := api.commits[0].Pipeline.Prompt.Context[0].Configuration
+	if _, exists := context["path"]; exists || !reflect.DeepEqual(context["assetId"], want) {
+		t.Errorf("committed context = %v, want assetId %v and no path", context, want)
+	}
+}
+
+func pipelineYaml(name string) string {
+	return "kind: floom/pipeline/1.2\npipeline:\n  name: " + name + "\n  model:\n    - package: floom/model/connector/openai\n      model: gpt-4\n"
+}
+
+// chdir changes the working directory for the rest of a test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"FloomCLI/floomapi"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Exit codes of the Floom CLI. They are documented in the README for scripts and CI, so
+// existing codes must not change.
+const (
+	exitFailure    = 1 // any other failure
+	exitUsage      = 2 // invalid arguments or flags
+	exitConfig     = 3 // the configuration or credentials cannot be read or written
+	exitNetwork    = 4 // a server cannot be reached or fails
+	exitAuth       = 5 // an API key is missing or rejected
+	exitValidation = 6 // a pipeline, template or setting is invalid
+	exitPartial    = 7 // some of several operations failed
+)
+
+// exitError is an error that ends the CLI with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// newExitError formats an error like fmt.Errorf, including %w, that ends the CLI with code.
+func newExitError(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// usageError marks an error of cobra's argument or flag parsing as a usage error.
+func usageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitUsage, err: err}
+}
+
+// exitCode returns the exit code of an error. Errors without an explicit code are
+// classified by their cause: API errors by their category and failed requests as network
+// errors.
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	var apiErr *floomapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Category {
+		case floomapi.CategoryAuth:
+			return exitAuth
+		case floomapi.CategoryValidation:
+			return exitValidation
+		case floomapi.CategoryServer, floomapi.CategoryRateLimit:
+			return exitNetwork
+		}
+		return exitFailure
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return exitNetwork
+	}
+	return exitFailure
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"FloomCLI/floomapi"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	apiError := func(category floomapi.ErrorCategory) error {
+		return fmt.Errorf("failed to deploy pipeline: %w", &floomapi.Error{Operation: "pipeline commit", Category: category})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "plain error", err: errors.New("failed"), want: exitFailure},
+		{name: "explicit code", err: newExitError(exitConfig, "failed to save: %w", errors.New("disk full")), want: exitConfig},
+		{name: "usage", err: usageError(errors.New("unknown flag")), want: exitUsage},
+		{name: "outer code wins", err: newExitError(exitPartial, "deployed 1 of 2 pipelines: %w", newExitError(exitValidation, "invalid")), want: exitPartial},
+		{name: "auth", err: apiError(floomapi.CategoryAuth), want: exitAuth},
+		{name: "validation", err: apiError(floomapi.CategoryValidation), want: exitValidation},
+		{name: "server", err: apiError(floomapi.CategoryServer), want: exitNetwork},
+		{name: "rate limit", err: apiError(floomapi.CategoryRateLimit), want: exitNetwork},
+		{name: "not found", err: apiError(floomapi.CategoryNotFound), want: exitFailure},
+		{name: "network", err: fmt.Errorf("error sending request: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), want: exitNetwork},
+		{name: "deadline", err: fmt.Errorf("error sending request: %w", context.DeadlineExceeded), want: exitNetwork},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(test.err); got != test.want {
+				t.Errorf("exitCode(%v) = %d, want %d", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"FloomCLI/floomapi"
+	"FloomCLI/models"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeAPI is an in-memory floomapi.API. Errors set on it are returned by every call of the
+// operation; commitErrors fails the commits of the named pipelines only.
+type fakeAPI struct {
+	mu           sync.Mutex
+	registration floomapi.Registration
+	registerErr  error
+	uploadErr    error
+	verifyErr    error
+	commitErrors map[string]error
+
+	registered int
+	uploads    []string
+	commits    []models.PipelineDto
+	apiKeys    []string
+}
+
+// useFakeAPI makes the commands of a test use api instead of a Floom server.
+func useFakeAPI(t *testing.T, api *fakeAPI) {
+	t.Helper()
+	previous := newAPIClient
+	newAPIClient = func(deploymentType string, credentials floomapi.Credentials) (floomapi.API, error) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.apiKeys = append(api.apiKeys, credentials.ApiKey)
+		return api, nil
+	}
+	t.Cleanup(func() { newAPIClient = previous })
+}
+
+func (api *fakeAPI) Register(ctx context.Context) (*floomapi.Registration, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.registerErr != nil {
+		return nil, api.registerErr
+	}
+	api.registered++
+	registration := api.registration
+	return &registration, nil
+}
+
+func (api *fakeAPI) UploadAsset(ctx context.Context, filePath string, progress floomapi.UploadProgress) (string, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.uploadErr != nil {
+		return "", api.uploadErr
+	}
+	api.uploads = append(api.uploads, filePath)
+	return fmt.Sprintf("asset-%d-%s", len(api.uploads), filepath.Base(filePath)), nil
+}
+
+func (api *fakeAPI) CommitPipeline(ctx context.Context, pipeline models.PipelineDto) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if err := api.commitErrors[pipeline.Pipeline.Name]; err != nil {
+		return err
+	}
+	api.commits = append(api.commits, pipeline)
+	return nil
+}
+
+func (api *fakeAPI) VerifyApiKey(ctx context.Context) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.verifyErr
+}
+
+func (api *fakeAPI) Health(ctx context.Context) (*floomapi.ServerStatus, error) {
+	return &floomapi.ServerStatus{Status: "OK"}, nil
+}
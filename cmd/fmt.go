@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"FloomCLI/utils"
+	"bytes"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var fmtCheck bool
+
+// fmtCmd represents the fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Rewrites pipeline files in the canonical format",
+	Long: `Rewrites pipeline YAML files in the canonical Floom format.
+
+The canonical format orders stages as model, prompt, response, global, puts 'package' first
+inside every plugin block, uses plain scalars unless quoting is required, indents with two
+spaces and turns single-string context 'path' values into lists. Comments are preserved.
+
+Examples:
+  # Format a pipeline file in place
+  floom fmt pipeline.yml
+
+  # Report unformatted files without changing them (exits with status 1 if any)
+  floom fmt --check ymls/*.yml
+
+  # Format standard input to standard output
+  cat pipeline.yml | floom fmt -`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result := fmtResult{Files: []string{}}
+		toStdout := false
+
+		for _, file := range args {
+			changed, err := formatPipelineFile(file, fmtCheck)
+			if err != nil {
+				return newExitError(exitValidation, "failed to format %s: %w", file, err)
+			}
+			if changed {
+				result.Files = append(result.Files, file)
+			}
+			toStdout = toStdout || (file == "-" && !fmtCheck)
+		}
+
+		// Formatted standard input is the output itself
+		if !toStdout {
+			if err := printResult(result); err != nil {
+				return err
+			}
+		}
+
+		// Unformatted files exit with status 1, as documented for --check
+		if fmtCheck && len(result.Files) > 0 {
+			return newExitError(exitFailure, "%d of %d files are not formatted", len(result.Files), len(args))
+		}
+		return nil
+	},
+}
+
+// fmtResult is the output of 'floom fmt': the files that were not formatted, and were
+// rewritten unless --check is set.
+type fmtResult struct {
+	Files []string `json:"files" yaml:"files"`
+}
+
+func (result fmtResult) printText() {
+	for _, file := range result.Files {
+		fmt.Println(file)
+	}
+}
+
+// formatPipelineFile formats a single file, or stdin when file is "-", and reports
+// whether its content differed from the canonical format.
+func formatPipelineFile(file string, check bool) (bool, error) {
+	if file == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return false, err
+		}
+		formatted, err := utils.FormatYaml(data)
+		if err != nil {
+			return false, err
+		}
+		if check {
+			return !bytes.Equal(data, formatted), nil
+		}
+		_, err = os.Stdout.Write(formatted)
+		return false, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := utils.FormatYaml(data)
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+
+	if !check {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(file, formatted, info.Mode()); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "Only report files that are not formatted, exit with status 1 if any")
+	rootCmd.AddCommand(fmtCmd)
+}
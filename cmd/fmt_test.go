@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmt(t *testing.T) {
+	const unformatted = "pipeline: {model: [{model: gpt-4, package: floom/model/connector/openai}], name: docs}\n"
+	const formatted = `pipeline:
+  name: docs
+  model:
+    - package: floom/model/connector/openai
+      model: gpt-4
+`
+
+	tests := []struct {
+		name     string
+		content  string
+		check    bool
+		wantCode int
+		want     string
+	}{
+		{name: "check unformatted", content: unformatted, check: true, wantCode: exitFailure, want: unformatted},
+		{name: "check formatted", content: formatted, check: true, want: formatted},
+		{name: "format", content: unformatted, want: formatted},
+		{name: "format formatted", content: formatted, want: formatted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "pipeline.yml")
+			if err := os.WriteFile(file, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			args := []string{"fmt", file}
+			if test.check {
+				args = append(args, "--check")
+			}
+			if _, code := runCommand(t, args...); code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+
+			if content, _ := os.ReadFile(file); string(content) != test.want {
+				t.Errorf("file content =\n%s\nwant\n%s", content, test.want)
+			}
+		})
+	}
+}
@@ -4,38 +4,73 @@ import (
 	"FloomCLI/config" // Import your config package
 	"fmt"
 	"github.com/spf13/cobra"
-	"os"
-	"path/filepath"
 )
 
 // infoCmd represents the info command
 var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Displays the configuration file path and its content",
-	Long:  `This command fetches and displays the configuration file path along with its content.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		displayConfigInfo()
+	Long: `This command displays the configuration file in use, the project file, the active profile
+and all settings, with API keys masked. The settings are the ones of 'floom config list'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return displayConfigInfo()
 	},
 }
 
-func displayConfigInfo() {
-	// Assuming GetConfigPath is a function that returns the full path of the config file
-	configDirPath, err := config.GetConfigPath("floom-cli") // Adjust the argument as per your actual config path function
+// configInfo is the view of the configuration shown by 'floom info'.
+type configInfo struct {
+	ConfigFile        string      `json:"config_file" yaml:"config_file"`
+	ProjectFile       string      `json:"project_file,omitempty" yaml:"project_file,omitempty"`
+	ReadOnly          bool        `json:"read_only" yaml:"read_only"`
+	Profile           string      `json:"profile" yaml:"profile"`
+	CredentialBackend string      `json:"credential_backend" yaml:"credential_backend"`
+	Settings          interface{} `json:"settings" yaml:"settings"`
+}
+
+func displayConfigInfo() error {
+	// The path honours --config and FLOOM_CONFIG
+	configFilePath, err := config.ConfigFilePath()
 	if err != nil {
-		fmt.Printf("Error fetching config path: %v\n", err)
-		return
+		return newExitError(exitConfig, "failed to fetch config path: %w", err)
 	}
-	configFilePath := filepath.Join(configDirPath, "config.json")
-	fmt.Println("Configuration File Path:", configFilePath)
-
-	// Read and display the content of the config file using os.ReadFile
-	content, err := os.ReadFile(configFilePath)
+	settings, err := config.GetValue("")
 	if err != nil {
-		fmt.Printf("Error reading config file: %v\n", err)
-		return
+		return newExitError(exitConfig, "%w", err)
+	}
+	_, projectPath, _ := config.Project()
+
+	info := configInfo{
+		ConfigFile:        configFilePath,
+		ProjectFile:       projectPath,
+		ReadOnly:          config.ReadOnly(),
+		Profile:           config.ActiveProfileName(),
+		CredentialBackend: config.GetConfig().CredentialBackend(),
+		Settings:          settings,
+	}
+	if err := printResult(info); err != nil {
+		return err
+	}
+
+	if len(config.PlaintextApiKeys()) > 0 && info.CredentialBackend != config.BackendPlaintext {
+		printNote("Some API keys are stored in plaintext. Run 'floom credentials migrate' to move them to the credential store.")
+	}
+	return nil
+}
+
+func (info configInfo) printText() {
+	fmt.Println("Configuration File Path:", info.ConfigFile)
+	if info.ProjectFile != "" {
+		fmt.Println("Project File Path:", info.ProjectFile)
+	}
+	if info.ReadOnly {
+		fmt.Println("Read-only: changes are not saved")
+	}
+	fmt.Println("Profile:", info.Profile)
+	fmt.Println("Credential backend:", info.CredentialBackend)
+	fmt.Println("Settings:")
+	for _, setting := range config.Flatten("", info.Settings) {
+		fmt.Printf("  %s = %s\n", setting.Key, config.FormatValue(setting.Value))
 	}
-	fmt.Println("Configuration Content:")
-	fmt.Println(string(content))
 }
 
 func init() {
@@ -2,7 +2,8 @@ package cmd
 
 import (
 	"FloomCLI/config"
-	"FloomCLI/utils"
+	"FloomCLI/floomapi"
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
@@ -14,58 +15,92 @@ var initCmd = &cobra.Command{
 	Short: "Initializes the Floom CLI by registering a new user if necessary",
 	Long:  `This command checks for an existing API key and, if one isn't found, prompts for a deployment type before registering a new user with Floom.`,
 	Args:  cobra.MinimumNArgs(0),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		var deploymentType string
 		if len(args) > 0 {
 			deploymentType = args[0]
+		} else if defaultTarget := config.DefaultTarget(); defaultTarget != "" {
+			deploymentType = defaultTarget
 		} else {
 			// Prompt for deployment type if not provided as an argument
-			fmt.Println("Please enter the deployment type (local, cloud, or custom endpoint):")
+			fmt.Fprintln(os.Stderr, "Please enter the deployment type (local, cloud, or custom endpoint):")
 			fmt.Scanln(&deploymentType)
 		}
 
 		// Continue with the existing logic...
-		initializeConfigForDeployment(deploymentType)
+		result, err := initializeConfigForDeployment(cmd.Context(), deploymentType)
+		if err != nil {
+			return err
+		}
+		return printResult(result)
 	},
 }
 
-func initializeConfigForDeployment(deploymentType string) {
+// initResult is the output of 'floom init'. Registered is false when the deployment
+// already had an API key.
+type initResult struct {
+	Target     string `json:"target" yaml:"target"`
+	Username   string `json:"username,omitempty" yaml:"username,omitempty"`
+	Nickname   string `json:"nickname,omitempty" yaml:"nickname,omitempty"`
+	Registered bool   `json:"registered" yaml:"registered"`
+}
+
+func (result *initResult) printText() {
+	if !result.Registered {
+		fmt.Println("API key already exists for this deployment type. No need to register a new user.")
+		return
+	}
+	fmt.Println("New user registered and configuration updated successfully.")
+}
+
+// initializeConfigForDeployment registers a new user for a deployment without an API key.
+func initializeConfigForDeployment(ctx context.Context, deploymentType string) (*initResult, error) {
 	// Attempt to initialize configuration
 	err := config.InitConfig()
 	if err != nil {
-		fmt.Printf("Error initializing configuration: %v\n", err)
-		os.Exit(1)
+		return nil, newExitError(exitConfig, "failed to initialize configuration: %w", err)
 	}
 
 	// Check if API key already exists
-	appConfig := config.GetConfig()
-	if deployment, exists := appConfig.Deployments[deploymentType]; exists && deployment.Credentials.ApiKey != "" {
-		fmt.Println("API key already exists for this deployment type. No need to register a new user.")
-		return
+	if deployment, exists := config.ActiveProfile().Deployments[deploymentType]; exists && deployment.Credentials.HasApiKey() {
+		credentials := deployment.Credentials
+		return &initResult{Target: deploymentType, Username: credentials.Username, Nickname: credentials.Nickname}, nil
 	}
 
 	// Validate deployment type
 	if deploymentType != "local" && deploymentType != "cloud" {
-		fmt.Println("Invalid deployment type. Please use 'local', 'cloud', or a valid custom endpoint.")
-		return
+		return nil, newExitError(exitUsage, "invalid deployment type '%s', use 'local' or 'cloud'", deploymentType)
+	}
+
+	// The API key of the new user can only be issued once, so the store must accept it
+	if err := config.PrepareCredentialStore(); err != nil {
+		return nil, newExitError(exitConfig, "failed to prepare the credential store: %w", err)
 	}
 
 	// Register a new user
-	registrationResponse, err := utils.RegisterUser(deploymentType)
+	client, err := newAPIClient(deploymentType, floomapi.Credentials{})
 	if err != nil {
-		fmt.Printf("Error registering new user: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+
+	registrationResponse, err := client.Register(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register new user: %w", err)
 	}
 
 	// Update the configuration with new user details for the specified deployment type
 	err = config.UpdateUserConfig(registrationResponse.ApiKey, registrationResponse.Username, registrationResponse.Nickname, deploymentType)
 	if err != nil {
-		fmt.Printf("Error updating configuration with new user details: %v\n", err)
-		os.Exit(1)
+		return nil, newExitError(exitConfig, "failed to update configuration with new user details: %w", err)
 	}
 
-	fmt.Println("New user registered and configuration updated successfully.")
+	return &initResult{
+		Target:     deploymentType,
+		Username:   registrationResponse.Username,
+		Nickname:   registrationResponse.Nickname,
+		Registered: true,
+	}, nil
 }
 
 func init() {
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"FloomCLI/floomapi"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	serverErr := &floomapi.Error{Operation: "registration", StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Category: floomapi.CategoryServer}
+
+	// The steps share a profile and run in order
+	profile := newProfile(t)
+	tests := []struct {
+		name           string
+		args           []string
+		registerErr    error
+		wantCode       int
+		wantRegistered bool
+	}{
+		{name: "invalid target", args: []string{"init", "staging"}, wantCode: exitUsage},
+		{name: "registration fails", args: []string{"init", "local"}, registerErr: serverErr, wantCode: exitNetwork},
+		{name: "registers a new user", args: []string{"init", "local"}, wantRegistered: true},
+		{name: "keeps the existing user", args: []string{"init", "local"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := &fakeAPI{
+				registration: floomapi.Registration{ApiKey: "key-of-ada", Username: "ada", Nickname: "Ada"},
+				registerErr:  test.registerErr,
+			}
+			useFakeAPI(t, api)
+
+			output, code := runCommand(t, append(test.args, "--profile", profile, "--output", "json")...)
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if code != 0 {
+				return
+			}
+
+			var result initResult
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, output)
+			}
+			if result.Registered != test.wantRegistered || result.Username != "ada" {
+				t.Errorf("result = %+v, want registered %v for 'ada'", result, test.wantRegistered)
+			}
+
+			// The key is kept in the credential store, not in config.json
+			credentials := config.ActiveProfile().Deployments["local"].Credentials
+			if credentials.ApiKey != "" || credentials.ApiKeyRef == "" {
+				t.Errorf("credentials = %+v, want a reference to the credential store", credentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"FloomCLI/floomapi"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	loginTarget      string
+	loginAPIKeyStdin bool
+	loginUsername    string
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs in to a Floom deployment with an existing API key",
+	Long: `Logs in to a Floom deployment with the API key of an existing account. The key is checked
+against the server before it is saved, replacing the credentials stored for the target.
+
+The API key is read from, in order:
+  - standard input, with --api-key-stdin
+  - the FLOOM_API_KEY environment variable
+  - an interactive prompt, which does not echo the key
+
+The server does not tell which account a key belongs to. Give the username with
+--username to have it shown by 'floom whoami'.
+
+To register a new anonymous user instead, use 'floom init'.
+
+Examples:
+  # Log in to Floom cloud, entering the key at the prompt
+  floom login
+
+  # Log in to a custom endpoint from a script
+  echo "$KEY" | floom login --target https://floom.example.com --api-key-stdin --username ci`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loginTarget = targetOrDefault(cmd, loginTarget)
+		if err := validateLoginTarget(loginTarget); err != nil {
+			return usageError(err)
+		}
+
+		apiKey, err := readAPIKey(loginAPIKeyStdin)
+		if err != nil {
+			return newExitError(exitAuth, "failed to read API key: %w", err)
+		}
+
+		client, err := newAPIClient(loginTarget, floomapi.Credentials{ApiKey: apiKey})
+		if err != nil {
+			return err
+		}
+
+		if err := client.VerifyApiKey(cmd.Context()); err != nil {
+			var apiErr *floomapi.Error
+			if errors.As(err, &apiErr) && apiErr.Category == floomapi.CategoryAuth {
+				return newExitError(exitAuth, "the API key was rejected by '%s'", loginTarget)
+			}
+			return fmt.Errorf("failed to verify API key: %w", err)
+		}
+
+		// Mention it when the credentials of another account are replaced
+		previous := config.ActiveProfile().Deployments[loginTarget].Credentials
+		if previous.HasApiKey() && previous.Username != "" && previous.Username != loginUsername {
+			printNote("Replacing the credentials of user '%s'.", previous.Username)
+		}
+
+		if err := config.UpdateUserConfig(apiKey, loginUsername, "", loginTarget); err != nil {
+			return newExitError(exitConfig, "failed to save credentials: %w", err)
+		}
+
+		return printResult(loginResult{Target: loginTarget, Username: loginUsername})
+	},
+}
+
+// loginResult is the output of 'floom login'.
+type loginResult struct {
+	Target   string `json:"target" yaml:"target"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+}
+
+func (result loginResult) printText() {
+	if result.Username == "" {
+		fmt.Printf("Logged in to '%s'.\n", result.Target)
+		return
+	}
+	fmt.Printf("Logged in to '%s' as %s.\n", result.Target, result.Username)
+}
+
+// validateLoginTarget accepts 'cloud' and custom endpoint URLs. Local deployments do not
+// use API keys.
+func validateLoginTarget(target string) error {
+	if target == "local" || target == "localhost" {
+		return fmt.Errorf("local deployments do not use API keys, no login is needed")
+	}
+	if target == "cloud" {
+		return nil
+	}
+
+	endpoint, err := url.Parse(target)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("invalid target '%s', use 'cloud' or an http(s) endpoint URL", target)
+	}
+	return nil
+}
+
+// readAPIKey reads an API key from stdin, the environment or an interactive prompt. Keys
+// are not accepted as flags, which would leave them in the shell history.
+func readAPIKey(fromStdin bool) (string, error) {
+	var apiKey string
+
+	switch {
+	case fromStdin:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no API key on standard input")
+		}
+		apiKey = line
+	case os.Getenv(config.ApiKeyEnvVar) != "":
+		apiKey = os.Getenv(config.ApiKeyEnvVar)
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		fmt.Fprint(os.Stderr, "API key: ")
+		input, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		apiKey = string(input)
+	default:
+		return "", fmt.Errorf("no API key provided, use --api-key-stdin or %s when not running in a terminal", config.ApiKeyEnvVar)
+	}
+
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("the API key is empty")
+	}
+	return apiKey, nil
+}
+
+// describeUser formats a user as "username (nickname)". Users logged in without a username
+// are shown as unknown.
+func describeUser(username, nickname string) string {
+	if username == "" {
+		return "unknown user"
+	}
+	if nickname == "" || nickname == username {
+		return username
+	}
+	return fmt.Sprintf("%s (%s)", username, nickname)
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().StringVar(&loginTarget, "target", "cloud", "Deployment to log in to: 'cloud' or a custom endpoint URL (FLOOM_TARGET or the project file override the default)")
+	loginCmd.Flags().BoolVar(&loginAPIKeyStdin, "api-key-stdin", false, "Read the API key from standard input")
+	loginCmd.Flags().StringVar(&loginUsername, "username", "", "Username of the account, stored for 'floom whoami'")
+}
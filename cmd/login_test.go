@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"FloomCLI/floomapi"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var (
+	rejectedErr = &floomapi.Error{Operation: "API key verification", StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Category: floomapi.CategoryAuth}
+	unavailable = &floomapi.Error{Operation: "API key verification", StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Category: floomapi.CategoryServer}
+)
+
+func TestLogin(t *testing.T) {
+	// The steps share a profile and run in order
+	profile := newProfile(t)
+	tests := []struct {
+		name         string
+		args         []string
+		apiKey       string
+		verifyErr    error
+		wantCode     int
+		wantUsername string
+	}{
+		{name: "local target", args: []string{"--target", "local"}, apiKey: "key-of-ada", wantCode: exitUsage},
+		{name: "no API key", wantCode: exitAuth},
+		{name: "key rejected", apiKey: "wrong-key", verifyErr: rejectedErr, wantCode: exitAuth},
+		{name: "server unavailable", apiKey: "key-of-ada", verifyErr: unavailable, wantCode: exitNetwork},
+		{name: "without username", apiKey: "key-of-ada"},
+		{name: "with username", args: []string{"--username", "ada"}, apiKey: "key-of-ada", wantUsername: "ada"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(config.ApiKeyEnvVar, test.apiKey)
+			api := &fakeAPI{verifyErr: test.verifyErr}
+			useFakeAPI(t, api)
+
+			output, code := runCommand(t, append([]string{"login", "--profile", profile, "--output", "json"}, test.args...)...)
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			credentials := config.ActiveProfile().Deployments["cloud"].Credentials
+			if code != 0 {
+				if credentials.HasApiKey() {
+					t.Errorf("credentials = %+v, want none after a failed login", credentials)
+				}
+				return
+			}
+
+			var result loginResult
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, output)
+			}
+			if result != (loginResult{Target: "cloud", Username: test.wantUsername}) {
+				t.Errorf("result = %+v, want cloud as %q", result, test.wantUsername)
+			}
+			if !reflect.DeepEqual(api.apiKeys, []string{test.apiKey}) {
+				t.Errorf("verified keys %v, want %q", api.apiKeys, test.apiKey)
+			}
+			if credentials.Username != test.wantUsername || credentials.ApiKey != "" || credentials.ApiKeyRef == "" {
+				t.Errorf("credentials = %+v, want %q with a reference to the credential store", credentials, test.wantUsername)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	profile := newProfile(t)
+	useFakeAPI(t, &fakeAPI{})
+	t.Setenv(config.ApiKeyEnvVar, "key-of-ada")
+	if _, code := runCommand(t, "login", "--profile", profile, "--username", "ada"); code != 0 {
+		t.Fatalf("login failed with exit code %d", code)
+	}
+
+	for _, want := range []bool{true, false} {
+		output, code := runCommand(t, "logout", "--profile", profile, "--output", "json")
+		if code != 0 {
+			t.Fatalf("logout failed with exit code %d", code)
+		}
+		var result logoutResult
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, output)
+		}
+		if result != (logoutResult{Target: "cloud", LoggedOut: want}) {
+			t.Errorf("result = %+v, want logged out %v", result, want)
+		}
+		if credentials := config.ActiveProfile().Deployments["cloud"].Credentials; credentials != (config.DeploymentCredentials{}) {
+			t.Errorf("credentials = %+v after logout, want none", credentials)
+		}
+	}
+}
+
+func TestWhoAmI(t *testing.T) {
+	profile := newProfile(t)
+	useFakeAPI(t, &fakeAPI{})
+	t.Setenv(config.ApiKeyEnvVar, "stored-key")
+	if _, code := runCommand(t, "login", "--profile", profile, "--username", "ada"); code != 0 {
+		t.Fatalf("login failed with exit code %d", code)
+	}
+
+	// The stored key is verified, not the one in the environment
+	t.Setenv(config.ApiKeyEnvVar, "environment-key")
+	tests := []struct {
+		name         string
+		args         []string
+		verifyErr    error
+		wantCode     int
+		wantStatus   string
+		wantVerified []string
+	}{
+		{name: "verified", wantStatus: "verified", wantVerified: []string{"stored-key"}},
+		{name: "rejected", verifyErr: rejectedErr, wantCode: exitAuth, wantStatus: "API key rejected, log in again", wantVerified: []string{"stored-key"}},
+		{name: "server unavailable", verifyErr: unavailable, wantCode: exitNetwork, wantVerified: []string{"stored-key"}},
+		{name: "offline", args: []string{"--offline"}},
+		{name: "other target", args: []string{"--target", "local"}, wantCode: exitAuth},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := &fakeAPI{verifyErr: test.verifyErr}
+			useFakeAPI(t, api)
+
+			output, code := runCommand(t, append([]string{"whoami", "--profile", profile, "--output", "json"}, test.args...)...)
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if !reflect.DeepEqual(api.apiKeys, test.wantVerified) {
+				t.Errorf("verified keys %v, want %v", api.apiKeys, test.wantVerified)
+			}
+			if output == "" {
+				return
+			}
+
+			var result whoamiResult
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, output)
+			}
+			if len(result.Users) != 1 || result.Users[0].Target != "cloud" || result.Users[0].Username != "ada" {
+				t.Fatalf("users = %+v, want ada on cloud", result.Users)
+			}
+			if user := result.Users[0]; test.wantStatus != "" && (user.Status != test.wantStatus || user.Verified != (test.wantCode == 0)) {
+				t.Errorf("user = %+v, want status %q", user, test.wantStatus)
+			}
+		})
+	}
+}
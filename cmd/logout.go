@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var logoutTarget string
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Removes the stored credentials of a Floom deployment",
+	Long: `Removes the API key, username and nickname stored for a deployment. The deployed pipelines
+and network settings of the deployment are kept in the configuration.
+
+The key itself stays valid on the server. Log in again with 'floom login'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logoutTarget = targetOrDefault(cmd, logoutTarget)
+		removed, err := config.RemoveCredentials(logoutTarget)
+		if err != nil {
+			return newExitError(exitConfig, "failed to remove credentials: %w", err)
+		}
+
+		return printResult(logoutResult{Target: logoutTarget, LoggedOut: removed})
+	},
+}
+
+// logoutResult is the output of 'floom logout'. LoggedOut is false when no credentials
+// were stored.
+type logoutResult struct {
+	Target    string `json:"target" yaml:"target"`
+	LoggedOut bool   `json:"logged_out" yaml:"logged_out"`
+}
+
+func (result logoutResult) printText() {
+	if !result.LoggedOut {
+		fmt.Printf("Not logged in to '%s'.\n", result.Target)
+		return
+	}
+	fmt.Printf("Logged out of '%s'.\n", result.Target)
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+	logoutCmd.Flags().StringVar(&logoutTarget, "target", "cloud", "Deployment to log out of: 'cloud' or a custom endpoint URL (FLOOM_TARGET or the project file override the default)")
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDir holds the configuration of all command tests. The configuration is loaded once
+// per run of the CLI, so the tests of this package share one file.
+var testDir string
+
+func TestMain(m *testing.M) {
+	var err error
+	testDir, err = os.MkdirTemp("", "floom-cmd-test-")
+	if err != nil {
+		panic(err)
+	}
+
+	// Never read the configuration or credentials of the user running the tests
+	for _, name := range []string{"FLOOM_API_KEY", "FLOOM_ENDPOINT", "FLOOM_TARGET", "FLOOM_PROFILE", "FLOOM_READ_ONLY", "FLOOM_RECORD", "FLOOM_REPLAY", "FLOOM_UPDATE_INDEX_URL", "FLOOM_UPDATE_PUBLIC_KEY"} {
+		os.Unsetenv(name)
+	}
+	os.Setenv("HOME", testDir)
+	os.Setenv("XDG_CONFIG_HOME", testDir)
+	os.Setenv("APPDATA", testDir)
+	os.Setenv("FLOOM_CONFIG", filepath.Join(testDir, "config.json"))
+	os.Setenv("FLOOM_CREDENTIALS_PASSPHRASE", "correct horse")
+
+	code := m.Run()
+	os.RemoveAll(testDir)
+	os.Exit(code)
+}
+
+// runCommand runs the CLI with args, like Execute, and returns what it printed to stdout
+// and its exit code.
+func runCommand(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	resetFlags(rootCmd)
+	argumentsValid = false
+
+	stdout := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = writer
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	rootCmd.SetArgs(args)
+	_, err = rootCmd.ExecuteContextC(context.Background())
+	writer.Close()
+	os.Stdout = stdout
+	printed := <-output
+
+	if err == nil {
+		return printed, 0
+	}
+	if !argumentsValid {
+		err = usageError(err)
+	}
+	t.Logf("floom %v: %v", args, err)
+	return printed, exitCode(err)
+}
+
+// resetFlags restores the defaults of the flags of a command and its subcommands, which
+// keep their values between runs.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+// profileCount numbers the profiles created by newProfile.
+var profileCount int
+
+// newProfile creates an empty profile for a test, so tests that store deployments do not
+// see each other's, also when they run several times.
+func newProfile(t *testing.T) string {
+	t.Helper()
+	profileCount++
+	name := fmt.Sprintf("test-%d", profileCount)
+	if _, code := runCommand(t, "profile", "create", name); code != 0 {
+		t.Fatalf("failed to create profile '%s'", name)
+	}
+	return name
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"FloomCLI/mockserver"
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	mockServerPort        int
+	mockServerHost        string
+	mockServerRequireAuth bool
+	mockServerVersion     string
+)
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Runs an in-memory fake Floom API for local testing",
+	Long: `Runs an in-memory fake of the Floom API, so pipelines can be deployed and tried without
+starting the docker-compose environment.
+
+The fake server implements user registration, asset uploads, pipeline commits and the
+health check. Committed pipelines are validated like on a real server, and pipeline
+invocations echo their prompt instead of calling a model. Everything is kept in memory
+and lost when the server stops.
+
+Uploaded assets and committed pipelines can be inspected at /v1/Assets and /v1/Pipelines.
+A pipeline is invoked like on Floom cloud, with a POST of {"prompt": "..."} to / and a
+Host header of <name>-<username>.pipeline.floom.ai, or just <name> for pipelines
+deployed without an API key.
+
+Examples:
+  # Serve on the port of a local Floom installation and deploy to it
+  floom mock-server --port 4050
+  floom deploy local pipeline.yml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server := mockserver.New()
+		server.Version = mockServerVersion
+		server.RequireAuth = mockServerRequireAuth
+		server.Logf = func(format string, args ...interface{}) {
+			fmt.Printf("%s "+format+"\n", append([]interface{}{time.Now().Format("15:04:05")}, args...)...)
+		}
+
+		address := net.JoinHostPort(mockServerHost, strconv.Itoa(mockServerPort))
+		if err := server.Start(address); err != nil {
+			return fmt.Errorf("failed to start mock server: %w", err)
+		}
+		fmt.Printf("Mock Floom API listening on %s, press Ctrl+C to stop.\n", server.URL())
+
+		<-cmd.Context().Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Close(ctx); err != nil {
+			return fmt.Errorf("failed to stop mock server: %w", err)
+		}
+		fmt.Println("Mock server stopped.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+	mockServerCmd.Flags().IntVar(&mockServerPort, "port", 4050, "Port to listen on, 0 picks a free port")
+	mockServerCmd.Flags().StringVar(&mockServerHost, "host", "127.0.0.1", "Address to listen on")
+	mockServerCmd.Flags().BoolVar(&mockServerRequireAuth, "require-auth", false, "Reject API keys of users that were not registered with this server")
+	mockServerCmd.Flags().StringVar(&mockServerVersion, "server-version", mockserver.DefaultVersion, "Server version reported by the health endpoint")
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats of --output.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+)
+
+// outputFormat is the format command results are printed in, set with --output.
+var outputFormat string
+
+// textPrinter is implemented by results with their own human readable form. Results
+// without one are printed as "key = value" lines.
+type textPrinter interface {
+	printText()
+}
+
+// tablePrinter is implemented by results that list entries, such as profiles, with a header
+// and one row per entry. Other results are printed as a table of keys and values.
+type tablePrinter interface {
+	tableRows() (header []string, rows [][]string)
+}
+
+// checkOutputFormat validates --output before a command runs.
+func checkOutputFormat() error {
+	switch outputFormat {
+	case outputText, outputJSON, outputYAML, outputTable:
+		return nil
+	}
+	return fmt.Errorf("unknown output format '%s', use text, json, yaml or table", outputFormat)
+}
+
+// structuredOutput reports whether results are printed for machines, as JSON or YAML.
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printNote prints a message that is not part of a command's result. It goes to stderr
+// when the result is printed as JSON or YAML, which keeps stdout parseable.
+func printNote(format string, args ...interface{}) {
+	if structuredOutput() {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// printResult prints the result of a command in the format selected with --output.
+func printResult(result interface{}) error {
+	return printValue("", result)
+}
+
+// printValue prints a value in the format selected with --output. Without a text or table
+// form of its own, the value is flattened to one setting per line or row, with keys below
+// key.
+func printValue(key string, value interface{}) error {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case outputYAML:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	case outputTable:
+		if table, ok := value.(tablePrinter); ok {
+			printTable(table.tableRows())
+			return nil
+		}
+	default:
+		if text, ok := value.(textPrinter); ok {
+			text.printText()
+			return nil
+		}
+	}
+
+	tree, err := genericValue(value)
+	if err != nil {
+		return err
+	}
+	settings := config.Flatten(key, tree)
+
+	if outputFormat == outputTable {
+		rows := make([][]string, 0, len(settings))
+		for _, setting := range settings {
+			rows = append(rows, []string{setting.Key, config.FormatValue(setting.Value)})
+		}
+		printTable([]string{"key", "value"}, rows)
+		return nil
+	}
+
+	// A single value is printed as it is
+	if len(settings) == 1 && settings[0].Key == key {
+		fmt.Println(config.FormatValue(tree))
+		return nil
+	}
+	for _, setting := range settings {
+		fmt.Printf("%s = %s\n", setting.Key, config.FormatValue(setting.Value))
+	}
+	return nil
+}
+
+// genericValue converts a value to its JSON form of maps, lists and scalars.
+func genericValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// printTable prints rows aligned in columns below an upper case header.
+func printTable(header []string, rows [][]string) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	writer.Flush()
+}
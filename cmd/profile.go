@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manages configuration profiles",
+	Long: `Profiles keep separate deployments, credentials and pipeline registries, e.g. for a
+personal cloud account, a team account and self-hosted endpoints.
+
+The profile of a command is selected with --profile, then the FLOOM_PROFILE environment
+variable, then the "profile" of a .floom.json project file, then the default set with
+'floom profile use'. Without any of them the 'default' profile is used.
+
+Examples:
+  floom profile create team
+  floom --profile team login
+  floom profile use team`,
+	// Profile commands work on profiles that may not exist yet, such as the one named by
+	// FLOOM_PROFILE, so the selection is not validated.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig(false)
+	},
+}
+
+// profileListCmd represents the profile list command
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the profiles, marking the active one",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		active := config.ActiveProfileName()
+		result := profileList{}
+		for _, name := range config.ProfileNames() {
+			deployments := config.GetConfig().Profile(name).Deployments
+			result = append(result, profileSummary{Name: name, Active: name == active, Deployments: len(deployments)})
+		}
+
+		return printResult(result)
+	},
+}
+
+// profileList is the output of 'floom profile list'.
+type profileList []profileSummary
+
+type profileSummary struct {
+	Name        string `json:"name" yaml:"name"`
+	Active      bool   `json:"active" yaml:"active"`
+	Deployments int    `json:"deployments" yaml:"deployments"`
+}
+
+func (profiles profileList) printText() {
+	for _, profile := range profiles {
+		marker := " "
+		if profile.Active {
+			marker = "*"
+		}
+		fmt.Printf("%s %s (%d deployments)\n", marker, profile.Name, profile.Deployments)
+	}
+}
+
+func (profiles profileList) tableRows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(profiles))
+	for _, profile := range profiles {
+		active := ""
+		if profile.Active {
+			active = "*"
+		}
+		rows = append(rows, []string{profile.Name, active, strconv.Itoa(profile.Deployments)})
+	}
+	return []string{"name", "active", "deployments"}, rows
+}
+
+// profileCreateCmd represents the profile create command
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates an empty profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.CreateProfile(args[0]); err != nil {
+			return newExitError(exitConfig, "failed to create profile: %w", err)
+		}
+		fmt.Printf("Profile '%s' created. Use it with --profile %s or 'floom profile use %s'.\n", args[0], args[0], args[0])
+		return nil
+	},
+}
+
+// profileCopyCmd represents the profile copy command
+var profileCopyCmd = &cobra.Command{
+	Use:   "copy <source> <name>",
+	Short: "Creates a profile as a copy of another one, including its credentials",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.CopyProfile(args[0], args[1]); err != nil {
+			return newExitError(exitConfig, "failed to copy profile: %w", err)
+		}
+		fmt.Printf("Profile '%s' copied to '%s'.\n", args[0], args[1])
+		return nil
+	},
+}
+
+// profileDeleteCmd represents the profile delete command
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a profile and its stored API keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DeleteProfile(args[0]); err != nil {
+			return newExitError(exitConfig, "failed to delete profile: %w", err)
+		}
+		fmt.Printf("Profile '%s' deleted.\n", args[0])
+		return nil
+	},
+}
+
+// profileUseCmd represents the profile use command
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Sets the profile used when neither --profile nor FLOOM_PROFILE is given",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.UseProfile(args[0]); err != nil {
+			return newExitError(exitConfig, "failed to select profile: %w", err)
+		}
+		fmt.Printf("Using profile '%s' by default.\n", args[0])
+		if name := os.Getenv("FLOOM_PROFILE"); name != "" && name != args[0] {
+			fmt.Printf("Note: FLOOM_PROFILE is set and selects '%s' in this shell.\n", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileCopyCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileUseCmd)
+}
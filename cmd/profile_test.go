@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	// The steps share the profiles and run in order
+	team := newProfile(t)
+	teamCopy := team + "-copy"
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "create existing", args: []string{"profile", "create", team}, wantCode: exitConfig},
+		{name: "create invalid name", args: []string{"profile", "create", "a/b"}, wantCode: exitConfig},
+		{name: "copy", args: []string{"profile", "copy", team, teamCopy}},
+		{name: "copy to existing", args: []string{"profile", "copy", team, teamCopy}, wantCode: exitConfig},
+		{name: "copy missing", args: []string{"profile", "copy", "missing", "other"}, wantCode: exitConfig},
+		{name: "use", args: []string{"profile", "use", team}},
+		{name: "use missing", args: []string{"profile", "use", "missing"}, wantCode: exitConfig},
+		{name: "select missing", args: []string{"--profile", "missing", "config", "list"}, wantCode: exitConfig},
+		{name: "delete default", args: []string{"profile", "delete", "default"}, wantCode: exitConfig},
+		{name: "delete active", args: []string{"profile", "delete", team}},
+		{name: "delete missing", args: []string{"profile", "delete", team}, wantCode: exitConfig},
+		{name: "usage", args: []string{"profile", "create"}, wantCode: exitUsage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, code := runCommand(t, test.args...); code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+		})
+	}
+
+	output, code := runCommand(t, "profile", "list", "--output", "json")
+	if code != 0 {
+		t.Fatalf("profile list exit code = %d", code)
+	}
+	var profiles profileList
+	if err := json.Unmarshal([]byte(output), &profiles); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	names := map[string]bool{}
+	for _, profile := range profiles {
+		names[profile.Name] = profile.Active
+	}
+	if _, exists := names[team]; exists || !names["default"] {
+		t.Errorf("profiles = %+v, want '%s' deleted and 'default' active again", profiles, team)
+	}
+	if _, exists := names[teamCopy]; !exists {
+		t.Errorf("profiles = %+v, want '%s'", profiles, teamCopy)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"FloomCLI/utils"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var (
+	renderVars   []string
+	renderTarget string
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render [file|-]",
+	Short: "Previews the rendered prompt template of a pipeline",
+	Long: `Renders the prompt template of a pipeline locally, replacing its {{placeholders}} with the
+values given with --var. The template is read from the file referenced by
+'prompt.template.file', or taken from the inline 'prompt.template.system' text.
+
+Examples:
+  # Render the prompt of a pipeline
+  floom render pipeline.yml --var name=Bob --var product=Floom
+
+  # Render the prompt as it would be deployed to the cloud, including the cloud overlay
+  floom render pipeline.yml --target cloud --var name=Bob`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		renderTarget = targetOrDefault(cmd, renderTarget)
+
+		vars := make(map[string]string, len(renderVars))
+		for _, assignment := range renderVars {
+			name, value, found := strings.Cut(assignment, "=")
+			if !found || name == "" {
+				return newExitError(exitUsage, "invalid --var '%s', expected name=value", assignment)
+			}
+			vars[name] = value
+		}
+
+		documents, err := loadPipelineDocuments(renderTarget, args[0])
+		if err != nil {
+			return newExitError(exitValidation, "failed to parse Floom YAML file: %w", err)
+		}
+
+		result := renderResult{}
+		for _, document := range documents {
+			content, ok, err := utils.TemplateContent(document)
+			if err != nil {
+				return newExitError(exitValidation, "failed to read prompt template of '%s': %w", document.Pipeline.Pipeline.Name, err)
+			}
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Pipeline '%s' has no prompt template\n", document.Pipeline.Pipeline.Name)
+				continue
+			}
+
+			rendered, err := utils.RenderTemplate(content, vars)
+			if err != nil {
+				return newExitError(exitValidation, "failed to render prompt template of '%s': %w", document.Pipeline.Pipeline.Name, err)
+			}
+
+			result = append(result, renderedPrompt{Pipeline: document.Pipeline.Pipeline.Name, Prompt: rendered})
+		}
+
+		return printResult(result)
+	},
+}
+
+// renderResult is the output of 'floom render': the rendered prompt of each pipeline.
+type renderResult []renderedPrompt
+
+type renderedPrompt struct {
+	Pipeline string `json:"pipeline" yaml:"pipeline"`
+	Prompt   string `json:"prompt" yaml:"prompt"`
+}
+
+func (prompts renderResult) printText() {
+	for _, prompt := range prompts {
+		if len(prompts) > 1 {
+			fmt.Printf("# %s\n", prompt.Pipeline)
+		}
+		fmt.Println(strings.TrimRight(prompt.Prompt, "\n"))
+	}
+}
+
+func init() {
+	renderCmd.Flags().StringArrayVar(&renderVars, "var", nil, "Placeholder value as name=value, can be repeated")
+	renderCmd.Flags().StringVar(&renderTarget, "target", "", "Deployment target whose overlay is merged before rendering (default from FLOOM_TARGET or the project file)")
+	renderCmd.Flags().StringVar(&deployBaseDir, "base-dir", "", "Directory relative file references are resolved against")
+	rootCmd.AddCommand(renderCmd)
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"FloomCLI/config"
+	"context"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 const floomAIHelpArt = `
@@ -15,6 +19,18 @@ const floomAIHelpArt = `
  | |    | | (_) | (_) | | | | | |  / ____ \ _| |_
  |_|    |_|\___/ \___/|_| |_| |_| /_/    \_\_____|`
 
+// verbose enables additional diagnostic output for all commands.
+var verbose bool
+
+// profileName selects the configuration profile, overriding FLOOM_PROFILE.
+var profileName string
+
+// Configuration flags, they override FLOOM_CONFIG and FLOOM_READ_ONLY.
+var (
+	configFile string
+	readOnly   bool
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "floom",
@@ -23,13 +39,68 @@ var rootCmd = &cobra.Command{
 with Floom environments directly from the command line. 
 ...
 (command-line toolset for the efficient management of Floom environments.)`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig(true)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// argumentsValid is set once cobra has parsed the flags and validated the arguments of the
+// command, so errors before it are usage errors.
+var argumentsValid bool
+
+// loadConfig checks the global flags, loads the configuration they select and selects the
+// profile of the run. It runs before every command, once flags are parsed.
+func loadConfig(validateProfile bool) error {
+	argumentsValid = true
+	if err := checkOutputFormat(); err != nil {
+		return usageError(err)
+	}
+
+	config.SetConfigFile(configFile)
+	config.SetReadOnly(readOnly)
+	if err := config.InitConfig(); err != nil {
+		return newExitError(exitConfig, "failed to load floom configuration file: %w", err)
+	}
+
+	if err := config.SelectProfile(profileName, validateProfile); err != nil {
+		return newExitError(exitConfig, "%w", err)
+	}
+	return nil
+}
+
+// targetOrDefault returns the value of a command's --target flag. When the flag is not
+// given, the target of FLOOM_TARGET or the project file replaces the flag's default.
+func targetOrDefault(cmd *cobra.Command, target string) string {
+	if !cmd.Flags().Changed("target") {
+		if defaultTarget := config.DefaultTarget(); defaultTarget != "" {
+			return defaultTarget
+		}
+	}
+	return target
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// Ctrl-C cancels the command context, which aborts requests that are in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	command, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil {
+		return
+	}
+	stop()
+
+	// Unknown commands, flags and invalid arguments fail before the command runs
+	if !argumentsValid {
+		err = usageError(err)
 	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	if exitCode(err) == exitUsage {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", command.CommandPath())
+	}
+	os.Exit(exitCode(err))
 }
 
 func init() {
@@ -39,5 +110,12 @@ func init() {
 		cmd.Usage()
 	})
 
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print additional diagnostic output, including every API request and response")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "debug", false, "Same as --verbose")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Configuration profile to use (default from FLOOM_PROFILE or 'floom profile use')")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file to use (default from FLOOM_CONFIG or the user configuration directory)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Never write the configuration or credentials, e.g. in CI (default from FLOOM_READ_ONLY)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json, yaml or table")
+
 	rootCmd.Root().CompletionOptions.DisableDefaultCmd = true
 }
@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"FloomCLI/utils"
+	"fmt"
 	"github.com/spf13/cobra"
-	"log"
 	"path/filepath"
 )
 
@@ -24,7 +24,7 @@ Examples:
 
   # Start using a specific Docker Compose file
   floom start path/to/your/docker-compose.yml`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Define the default Docker Compose file name
 		defaultComposeFile := "docker-compose.yml"
 		composeFilePath := ""
@@ -36,7 +36,7 @@ Examples:
 			// Search for the default Docker Compose file in the current directory
 			files, err := filepath.Glob("./*.yml")
 			if err != nil {
-				log.Fatalf("Error reading directory: %v", err)
+				return fmt.Errorf("failed to read directory: %w", err)
 			}
 			for _, file := range files {
 				if filepath.Base(file) == defaultComposeFile {
@@ -48,36 +48,53 @@ Examples:
 
 		// Check if a Docker Compose file has been identified or provided
 		if composeFilePath == "" {
-			log.Fatalf("No Docker Compose file found or provided")
-		} else {
-			log.Printf("Using Docker Compose file: %s", composeFilePath)
+			return newExitError(exitUsage, "no Docker Compose file found or provided")
 		}
+		printNote("Using Docker Compose file: %s", composeFilePath)
 
 		// Check if Docker Compose services are running and stop them if they are
-		log.Println("Checking if Docker Compose services are already running...")
+		printNote("Checking if Docker Compose services are already running...")
 		checkOutput, checkErr := utils.ExecuteShellCommand("docker-compose", []string{"-f", composeFilePath, "ps", "-q"})
 		if checkErr != nil {
-			log.Fatalf("Error checking Docker Compose services: %v", checkErr)
+			return fmt.Errorf("failed to check Docker Compose services: %w", checkErr)
 		}
 		if checkOutput != "" {
-			log.Println("Stopping running Docker Compose services...")
+			printNote("Stopping running Docker Compose services...")
 			_, downErr := utils.ExecuteShellCommand("docker-compose", []string{"-f", composeFilePath, "down"})
 			if downErr != nil {
-				log.Fatalf("Error stopping Docker Compose services: %v", downErr)
+				return fmt.Errorf("failed to stop Docker Compose services: %w", downErr)
 			}
 		}
 
 		// Restart the Docker Compose services
-		log.Printf("Starting Docker Compose with file: %s", composeFilePath)
+		printNote("Starting Docker Compose with file: %s", composeFilePath)
 		output, err := utils.ExecuteShellCommand("docker-compose", []string{"-f", composeFilePath, "-p", "floom", "up", "-d"})
 
 		if err != nil {
-			log.Fatalf("Error executing Docker Compose: %v\n%s", err, output)
+			return fmt.Errorf("failed to execute Docker Compose: %w\n%s", err, output)
 		}
-		log.Println("Docker Compose started successfully.")
+
+		result := environmentResult{ComposeFile: composeFilePath, Project: "floom", Status: "running"}
+		return printResult(result)
 	},
 }
 
+// environmentResult is the output of 'floom start' and 'floom stop': the Docker Compose
+// environment and whether it is running or stopped.
+type environmentResult struct {
+	ComposeFile string `json:"compose_file" yaml:"compose_file"`
+	Project     string `json:"project,omitempty" yaml:"project,omitempty"`
+	Status      string `json:"status" yaml:"status"`
+}
+
+func (result environmentResult) printText() {
+	if result.Status == "running" {
+		fmt.Println("Docker Compose started successfully.")
+		return
+	}
+	fmt.Println("Docker Compose stopped successfully.")
+}
+
 func init() {
 	rootCmd.AddCommand(startCmd)
 }
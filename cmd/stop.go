@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"FloomCLI/utils"
+	"fmt"
 	"github.com/spf13/cobra"
-	"log"
 	"path/filepath"
 )
 
@@ -22,7 +22,7 @@ Examples:
 
   # Stop using a specific Docker Compose file
   floom stop path/to/your/docker-compose.yml`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		defaultComposeFile := "docker-compose.yml"
 		composeFilePath := ""
 
@@ -33,7 +33,7 @@ Examples:
 			// Search for the default Docker Compose file in the current directory
 			files, err := filepath.Glob("./*.yml")
 			if err != nil {
-				log.Fatalf("Error reading directory: %v", err)
+				return fmt.Errorf("failed to read directory: %w", err)
 			}
 			for _, file := range files {
 				if filepath.Base(file) == defaultComposeFile {
@@ -45,18 +45,19 @@ Examples:
 
 		// Check if a Docker Compose file has been identified or provided
 		if composeFilePath == "" {
-			log.Fatalf("No Docker Compose file found or provided")
-		} else {
-			log.Printf("Using Docker Compose file: %s", composeFilePath)
+			return newExitError(exitUsage, "no Docker Compose file found or provided")
 		}
+		printNote("Using Docker Compose file: %s", composeFilePath)
 
 		// Stop the Docker Compose services
-		log.Println("Stopping Docker Compose services...")
+		printNote("Stopping Docker Compose services...")
 		_, err := utils.ExecuteShellCommand("docker-compose", []string{"-f", composeFilePath, "down"})
 		if err != nil {
-			log.Fatalf("Error stopping Docker Compose services: %v", err)
+			return fmt.Errorf("failed to stop Docker Compose services: %w", err)
 		}
-		log.Println("Docker Compose stopped successfully.")
+
+		result := environmentResult{ComposeFile: composeFilePath, Status: "stopped"}
+		return printResult(result)
 	},
 }
 
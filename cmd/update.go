@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"FloomCLI/internal/semver"
+	"FloomCLI/updater"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Environment variables of the update command. The public key is only used by builds without
+// an embedded release key, e.g. to test against a stand-in release server.
+const (
+	updateIndexEnvVar     = "FLOOM_UPDATE_INDEX_URL"
+	updatePublicKeyEnvVar = "FLOOM_UPDATE_PUBLIC_KEY"
+)
+
+// updateCheckTimeout bounds the check run of a freshly installed binary.
+const updateCheckTimeout = 30 * time.Second
+
+var (
+	updateChannel  string
+	updateVersion  string
+	updateIndexURL string
+	updateCheck    bool
+)
+
+// embeddedIndexURL and updatePublicKey are the release index and the base64 ed25519 public key
+// of the release signing key embedded by build.sh, set by main.
+var (
+	embeddedIndexURL string
+	updatePublicKey  string
+)
+
+// SetUpdateIndexURL sets the release index that build.sh injects with -ldflags.
+func SetUpdateIndexURL(url string) {
+	embeddedIndexURL = url
+}
+
+// SetUpdatePublicKey sets the public key that verifies downloaded releases, which build.sh
+// injects with -ldflags.
+func SetUpdatePublicKey(key string) {
+	updatePublicKey = key
+}
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the Floom CLI to the latest release",
+	Long: `Downloads the latest release of the selected channel from the release index and replaces
+the running binary with it. The download is verified with its SHA-256 checksum and the
+ed25519 signature of the Floom release key before it is installed. If the new binary
+fails to start, the previous one is restored.
+
+The release index is read from --index-url, then FLOOM_UPDATE_INDEX_URL, then the index
+of the release build. Builds without an embedded release key, such as development
+builds, verify updates with FLOOM_UPDATE_PUBLIC_KEY, e.g. to test against a local
+stand-in server.
+
+Examples:
+  floom update
+  floom update --check
+  floom update --channel beta
+  floom update --version v1.0.0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := runUpdate(cmd.Context(), cmd); err != nil {
+			return fmt.Errorf("failed to update Floom CLI: %w", err)
+		}
+		return nil
+	},
+}
+
+func runUpdate(ctx context.Context, cmd *cobra.Command) error {
+	indexURL := updateIndexURL
+	if indexURL == "" {
+		indexURL = os.Getenv(updateIndexEnvVar)
+	}
+	if indexURL == "" {
+		indexURL = embeddedIndexURL
+	}
+	if indexURL == "" {
+		return newExitError(exitConfig, "this build has no release index, pass --index-url or set %s", updateIndexEnvVar)
+	}
+	u := &updater.Updater{IndexURL: indexURL}
+
+	index, err := u.FetchIndex(ctx)
+	if err != nil {
+		return err
+	}
+	release, err := index.Select(updateChannel, updateVersion)
+	if err != nil {
+		return err
+	}
+
+	// Without --version, only newer releases are installed
+	result := updateResult{Current: buildInfo.Version, Release: release.Version, Channel: release.Channel}
+	comparison := semver.Compare(release.Version, result.Current)
+	if comparison == 0 || (comparison < 0 && updateVersion == "") {
+		return printResult(result)
+	}
+	result.Available = true
+	if updateCheck {
+		return printResult(result)
+	}
+
+	// The embedded release key cannot be replaced, so the environment cannot redirect updates
+	key := updatePublicKey
+	if envKey := os.Getenv(updatePublicKeyEnvVar); envKey != "" {
+		if key != "" {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s, this build verifies updates with its release key.\n", updatePublicKeyEnvVar)
+		} else {
+			key = envKey
+		}
+	}
+	if key == "" {
+		return newExitError(exitConfig, "this build has no release signing key and cannot verify updates, install a release build or set %s", updatePublicKeyEnvVar)
+	}
+	if u.PublicKey, err = updater.ParsePublicKey(key); err != nil {
+		return err
+	}
+
+	executable, err := updater.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running binary: %w", err)
+	}
+
+	// The download goes next to the binary, so it can be renamed over it
+	printNote("Downloading Floom CLI %s for %s...", release.Version, updater.Platform())
+	downloaded, err := u.Download(ctx, release, filepath.Dir(executable))
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("no permission to write to %s, run the update as the owner of the installation", filepath.Dir(executable))
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(downloaded)
+
+	err = updater.Replace(executable, downloaded, func(path string) error {
+		return checkInstalledVersion(ctx, path, release.Version)
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Updated = true
+	return printResult(result)
+}
+
+// updateResult is the output of 'floom update'. Available tells whether Release can be
+// installed, and Updated whether it was.
+type updateResult struct {
+	Current   string `json:"current_version" yaml:"current_version"`
+	Release   string `json:"release_version" yaml:"release_version"`
+	Channel   string `json:"channel" yaml:"channel"`
+	Available bool   `json:"available" yaml:"available"`
+	Updated   bool   `json:"updated" yaml:"updated"`
+}
+
+func (result updateResult) printText() {
+	switch {
+	case result.Updated:
+		fmt.Printf("Updated Floom CLI from %s to %s.\n", result.Current, result.Release)
+	case result.Available:
+		fmt.Printf("Floom CLI %s is available, this is %s. Run 'floom update' to install it.\n", result.Release, result.Current)
+	default:
+		fmt.Printf("Floom CLI %s is up to date.\n", result.Current)
+	}
+}
+
+// checkInstalledVersion runs a freshly installed binary and checks that it reports the
+// expected version. The binary runs read-only against an empty configuration directory, so
+// it cannot migrate or otherwise change the user's configuration before it is accepted.
+func checkInstalledVersion(ctx context.Context, path, expected string) error {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+
+	configDir, err := os.MkdirTemp("", "floom-update-check-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(configDir)
+
+	// Environment variables, unlike flags, are also understood or ignored by older releases
+	check := exec.CommandContext(ctx, path, "version", "--output", "json")
+	check.Env = append(os.Environ(),
+		"FLOOM_READ_ONLY=1",
+		"FLOOM_CONFIG="+filepath.Join(configDir, "config.json"),
+		"XDG_CONFIG_HOME="+configDir,
+		"APPDATA="+configDir,
+		"HOME="+configDir,
+	)
+	output, err := check.Output()
+	if err != nil {
+		return fmt.Errorf("running '%s version' failed: %w", path, err)
+	}
+
+	var installed BuildInfo
+	if err := json.Unmarshal(output, &installed); err != nil {
+		return fmt.Errorf("unexpected output of '%s version': %w", path, err)
+	}
+	if semver.Compare(installed.Version, expected) != 0 {
+		return fmt.Errorf("the installed binary reports version %s instead of %s", installed.Version, expected)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().StringVar(&updateChannel, "channel", updater.DefaultChannel, "Release channel, e.g. 'stable' or 'beta'")
+	updateCmd.Flags().StringVar(&updateVersion, "version", "", "Install this version instead of the latest one, also to downgrade")
+	updateCmd.Flags().StringVar(&updateIndexURL, "index-url", "", "URL of the release index (default: FLOOM_UPDATE_INDEX_URL, then the index of the release build)")
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only check whether an update is available")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"FloomCLI/updater"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	index := updater.Index{Releases: []updater.Release{
+		{Version: "v1.1.0", Channel: "stable"},
+		{Version: "v1.2.0-beta.1", Channel: "beta"},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(index)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		current       string
+		args          []string
+		wantCode      int
+		wantAvailable bool
+	}{
+		{name: "no release index", current: "v1.0.0", args: []string{"update", "--check"}, wantCode: exitConfig},
+		{name: "up to date", current: "v1.1.0", args: []string{"update", "--index-url", server.URL}},
+		{name: "newer than the channel", current: "v1.2.0", args: []string{"update", "--index-url", server.URL}},
+		{name: "update available", current: "v1.0.0", args: []string{"update", "--check", "--index-url", server.URL}, wantAvailable: true},
+		{name: "beta channel", current: "v1.1.0", args: []string{"update", "--check", "--channel", "beta", "--index-url", server.URL}, wantAvailable: true},
+		{name: "unknown version", current: "v1.0.0", args: []string{"update", "--version", "v9.0.0", "--index-url", server.URL}, wantCode: exitFailure},
+		{name: "no signing key", current: "v1.0.0", args: []string{"update", "--index-url", server.URL}, wantCode: exitConfig},
+		{name: "unreachable index", current: "v1.0.0", args: []string{"update", "--index-url", "http://127.0.0.1:1/index.json"}, wantCode: exitNetwork},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous := buildInfo.Version
+			buildInfo.Version = test.current
+			defer func() { buildInfo.Version = previous }()
+
+			output, code := runCommand(t, append(test.args, "--output", "json")...)
+			if code != test.wantCode {
+				t.Fatalf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if code != 0 {
+				return
+			}
+
+			var result updateResult
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, output)
+			}
+			if result.Available != test.wantAvailable || result.Updated {
+				t.Errorf("result = %+v, want available %v and not updated", result, test.wantAvailable)
+			}
+		})
+	}
+}
@@ -2,31 +2,169 @@ package cmd
 
 import (
 	"FloomCLI/config"
+	"FloomCLI/floomapi"
 	"fmt"
 	"github.com/spf13/cobra"
+	"os"
+	"runtime"
+	"runtime/debug"
 )
 
+var versionTarget string
+
+// BuildInfo identifies a build of the CLI.
+type BuildInfo struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty" yaml:"build_date,omitempty"`
+}
+
+// buildInfo is the build of this CLI, set by main.
+var buildInfo = BuildInfo{Version: "dev"}
+
+// SetBuildInfo sets the version, git commit and build date of this build, which build.sh
+// injects with -ldflags. A missing commit or date is taken from the version control
+// information recorded by the Go toolchain, if any.
+func SetBuildInfo(version, commit, buildDate string) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+				if len(commit) > 12 {
+					commit = commit[:12]
+				}
+			case setting.Key == "vcs.time" && buildDate == "":
+				buildDate = setting.Value
+			}
+		}
+	}
+
+	if version != "" {
+		buildInfo.Version = version
+	}
+	buildInfo.Commit = commit
+	buildInfo.BuildDate = buildDate
+}
+
+// versionResult is the output of 'floom version'.
+type versionResult struct {
+	BuildInfo           `yaml:",inline"`
+	GoVersion           string         `json:"go_version" yaml:"go_version"`
+	Platform            string         `json:"platform" yaml:"platform"`
+	ConfigSchemaVersion int            `json:"config_schema_version" yaml:"config_schema_version"`
+	Server              *serverVersion `json:"server,omitempty" yaml:"server,omitempty"`
+}
+
+// serverVersion is the health and version of the server of a deployment target. Warning
+// explains a known incompatibility with this CLI.
+type serverVersion struct {
+	Target  string `json:"target" yaml:"target"`
+	URL     string `json:"url" yaml:"url"`
+	Status  string `json:"status,omitempty" yaml:"status,omitempty"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	Warning string `json:"warning,omitempty" yaml:"warning,omitempty"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Displays the version of the Floom CLI",
-	Long:  `This command displays the current version of the Floom CLI application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		DisplayVersion() // Call the function to display the version
+	Long: `This command displays the version, git commit and build date of the Floom CLI, the Go
+version and platform it was built for, and the config schema version it writes.
+
+With --target, the health endpoint of the deployment's server is queried too, and a
+warning is shown if the server version is known to be incompatible with this CLI.
+
+Examples:
+  floom version
+  floom version --target cloud --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return DisplayVersion(cmd)
 	},
 }
 
-func DisplayVersion() {
-	err := config.InitConfig() // This will initialize the config if it doesn't exist.
-	if err != nil {
-		fmt.Printf("Error initializing or loading the configuration: %v\n", err)
+// DisplayVersion prints the version information. It fails if the server given with
+// --target could not be checked.
+func DisplayVersion(cmd *cobra.Command) error {
+	result := versionResult{
+		BuildInfo:           buildInfo,
+		GoVersion:           runtime.Version(),
+		Platform:            runtime.GOOS + "/" + runtime.GOARCH,
+		ConfigSchemaVersion: config.CurrentSchemaVersion,
+	}
+	if versionTarget != "" {
+		result.Server = checkServerVersion(cmd, versionTarget)
+	}
+
+	if result.Server != nil && result.Server.Warning != "" {
+		fmt.Fprintln(os.Stderr, "Warning:", result.Server.Warning)
+	}
+
+	if err := printResult(result); err != nil {
+		return err
+	}
+	if result.Server != nil && result.Server.Error != "" {
+		return newExitError(exitNetwork, "failed to check the server of '%s'", result.Server.Target)
+	}
+	return nil
+}
+
+func (result versionResult) printText() {
+	fmt.Println("Floom CLI Version:", result.Version)
+	if result.Commit != "" {
+		fmt.Println("Git Commit:", result.Commit)
+	}
+	if result.BuildDate != "" {
+		fmt.Println("Build Date:", result.BuildDate)
+	}
+	fmt.Println("Go Version:", result.GoVersion)
+	fmt.Println("OS/Arch:", result.Platform)
+	fmt.Println("Config Schema Version:", result.ConfigSchemaVersion)
+
+	server := result.Server
+	if server == nil {
 		return
 	}
+	name := server.Target
+	if server.URL != server.Target {
+		name += " (" + server.URL + ")"
+	}
+
+	switch {
+	case server.Error != "":
+		fmt.Printf("Server %s: %s\n", name, server.Error)
+	case server.Version != "":
+		fmt.Printf("Server %s: %s, version %s\n", name, server.Status, server.Version)
+	default:
+		fmt.Printf("Server %s: %s, version unknown\n", name, server.Status)
+	}
+}
+
+// checkServerVersion queries the health endpoint of a deployment target.
+func checkServerVersion(cmd *cobra.Command, target string) *serverVersion {
+	server := &serverVersion{Target: target, URL: apiBaseURL(target)}
+
+	// The health endpoint needs no API key
+	client, err := newAPIClient(target, floomapi.Credentials{})
+	if err != nil {
+		server.Error = err.Error()
+		return server
+	}
+	status, err := client.Health(cmd.Context())
+	if err != nil {
+		server.Error = err.Error()
+		return server
+	}
 
-	config := config.GetConfig() // Now we are sure that config is initialized.
-	fmt.Printf("Floom CLI Version: %s\n", config.ConfigVersion)
+	server.Status, server.Version = status.Status, status.Version
+	server.Warning = floomapi.CheckCompatibility(status.Version)
+	return server
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd) // Add the versionCmd to the root command
+	versionCmd.Flags().StringVar(&versionTarget, "target", "", "Also check the server of a deployment: 'local', 'cloud' or a custom endpoint URL")
 }
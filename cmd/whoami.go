@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"FloomCLI/config"
+	"FloomCLI/floomapi"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+)
+
+var (
+	whoamiTarget  string
+	whoamiOffline bool
+)
+
+// whoamiCmd represents the whoami command
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Shows the user logged in to each Floom deployment",
+	Long: `Shows the username and nickname stored for each deployment with credentials, or only for
+the deployment given with --target. Each stored API key is checked against its server
+unless --offline is set. A key set with FLOOM_API_KEY is not shown or checked, although
+other commands use it instead of the stored ones.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var targets []string
+		for target, deployment := range config.ActiveProfile().Deployments {
+			if deployment.Credentials.HasApiKey() && (whoamiTarget == "" || target == whoamiTarget) {
+				targets = append(targets, target)
+			}
+		}
+		sort.Strings(targets)
+
+		if len(targets) == 0 {
+			if whoamiTarget != "" {
+				return newExitError(exitAuth, "not logged in to '%s' in profile '%s'", whoamiTarget, config.ActiveProfileName())
+			}
+			return newExitError(exitAuth, "not logged in to any deployment in profile '%s', use 'floom login' or 'floom init'", config.ActiveProfileName())
+		}
+
+		if os.Getenv(config.ApiKeyEnvVar) != "" {
+			printNote("%s is set, other commands use it instead of the stored keys shown here.", config.ApiKeyEnvVar)
+		}
+
+		result := whoamiResult{Profile: config.ActiveProfileName()}
+		var failures []error
+		for _, target := range targets {
+			credentials := config.ActiveProfile().Deployments[target].Credentials
+			user := whoamiUser{Target: target, Username: credentials.Username, Nickname: credentials.Nickname}
+
+			if !whoamiOffline {
+				user.Status = "verified"
+				if err := verifyCredentials(cmd, target); err != nil {
+					user.Status = err.Error()
+					failures = append(failures, fmt.Errorf("'%s': %w", target, err))
+				}
+				user.Verified = user.Status == "verified"
+			}
+			result.Users = append(result.Users, user)
+		}
+
+		if err := printResult(result); err != nil {
+			return err
+		}
+
+		// A single failure keeps its own exit code
+		switch {
+		case len(failures) == 1 && len(targets) == 1:
+			return failures[0]
+		case len(failures) == len(targets):
+			return newExitError(exitCode(failures[0]), "no API key could be verified: %w", errors.Join(failures...))
+		case len(failures) > 0:
+			return newExitError(exitPartial, "%d of %d API keys could not be verified: %w", len(failures), len(targets), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+// whoamiResult is the output of 'floom whoami': the users logged in to the deployments of
+// a profile. Without --offline, Status tells whether each API key was verified.
+type whoamiResult struct {
+	Profile string       `json:"profile" yaml:"profile"`
+	Users   []whoamiUser `json:"users" yaml:"users"`
+}
+
+type whoamiUser struct {
+	Target   string `json:"target" yaml:"target"`
+	Username string `json:"username" yaml:"username"`
+	Nickname string `json:"nickname,omitempty" yaml:"nickname,omitempty"`
+	Status   string `json:"status,omitempty" yaml:"status,omitempty"`
+	Verified bool   `json:"verified" yaml:"verified"`
+}
+
+func (result whoamiResult) printText() {
+	fmt.Println("Profile:", result.Profile)
+	for _, user := range result.Users {
+		if user.Status == "" {
+			fmt.Printf("%s: %s\n", user.Target, describeUser(user.Username, user.Nickname))
+			continue
+		}
+		fmt.Printf("%s: %s [%s]\n", user.Target, describeUser(user.Username, user.Nickname), user.Status)
+	}
+}
+
+func (result whoamiResult) tableRows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(result.Users))
+	for _, user := range result.Users {
+		rows = append(rows, []string{user.Target, user.Username, user.Nickname, user.Status})
+	}
+	return []string{"target", "username", "nickname", "status"}, rows
+}
+
+// verifyCredentials checks the stored API key of a target against its server. The error
+// describes why it could not be verified.
+func verifyCredentials(cmd *cobra.Command, target string) error {
+	apiKey, err := config.GetStoredApiKey(target)
+	if err != nil {
+		return newExitError(exitAuth, "cannot verify: %w", err)
+	}
+
+	client, err := newAPIClient(target, floomapi.Credentials{ApiKey: apiKey})
+	if err != nil {
+		return fmt.Errorf("cannot verify: %w", err)
+	}
+
+	if err := client.VerifyApiKey(cmd.Context()); err != nil {
+		var apiErr *floomapi.Error
+		if errors.As(err, &apiErr) && apiErr.Category == floomapi.CategoryAuth {
+			return newExitError(exitAuth, "API key rejected, log in again")
+		}
+		return fmt.Errorf("cannot verify: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+	whoamiCmd.Flags().StringVar(&whoamiTarget, "target", "", "Only show the given deployment")
+	whoamiCmd.Flags().BoolVar(&whoamiOffline, "offline", false, "Show the stored credentials without checking them against the server")
+}
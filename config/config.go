@@ -18,18 +18,53 @@ type PipelineConfiguration struct {
 type DeploymentConfiguration struct {
 	Credentials DeploymentCredentials   `json:"credentials"`
 	Pipelines   []PipelineConfiguration `json:"pipelines"`
+	Network     *NetworkConfiguration   `json:"network,omitempty"`
 }
 
+// NetworkConfiguration holds the connection, timeout and retry settings for requests to a
+// deployment. Durations use Go duration syntax, e.g. "30s" or "5m". Endpoints overrides the
+// settings for single API endpoints: "register", "upload", "commit", "verify" or "health".
+type NetworkConfiguration struct {
+	RequestConfiguration
+	Endpoints map[string]RequestConfiguration `json:"endpoints,omitempty"`
+	TLS       *TLSConfiguration               `json:"tls,omitempty"`
+	ProxyURL  string                          `json:"proxy_url,omitempty"`
+}
+
+// TLSConfiguration holds the TLS settings for a deployment. Paths point to PEM files.
+type TLSConfiguration struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// RequestConfiguration holds timeout and retry settings. Empty fields keep their defaults.
+type RequestConfiguration struct {
+	Timeout        string `json:"timeout,omitempty"`
+	MaxRetries     *int   `json:"max_retries,omitempty"`
+	InitialBackoff string `json:"initial_backoff,omitempty"`
+	MaxBackoff     string `json:"max_backoff,omitempty"`
+}
+
+// DeploymentCredentials identify the user of a deployment. The API key is kept in the
+// credential store and ApiKeyRef points to it; ApiKey holds plaintext keys of the
+// "plaintext" backend and of configurations written by older versions.
 type DeploymentCredentials struct {
-	ApiKey   string `json:"api_key"`
-	Username string `json:"username"`
-	Nickname string `json:"nickname"`
+	ApiKey    string `json:"api_key,omitempty"`
+	ApiKeyRef string `json:"api_key_ref,omitempty"`
+	Username  string `json:"username"`
+	Nickname  string `json:"nickname"`
 }
 
-// AppConfig holds the application configuration, including apiKey, username, and nickname.
+// AppConfig holds the application configuration: named profiles, each with its own
+// deployments, credentials and pipelines, and the settings shared by all profiles.
+// SchemaVersion is the version of the file format, see CurrentSchemaVersion.
 type AppConfig struct {
-	ConfigVersion string                             `json:"config_version"`
-	Deployments   map[string]DeploymentConfiguration `json:"deployments"`
+	SchemaVersion   int                              `json:"schema_version"`
+	CredentialStore *CredentialStoreConfiguration    `json:"credential_store,omitempty"`
+	DefaultProfile  string                           `json:"default_profile,omitempty"`
+	Profiles        map[string]*ProfileConfiguration `json:"profiles"`
 }
 
 var (
@@ -45,7 +80,8 @@ func GetConfig() *AppConfig {
 	return appConfig
 }
 
-// GetConfigPath returns the path to the configuration directory for the app.
+// GetConfigPath returns the path to the configuration directory for the app. The directory
+// is not created here, but when the configuration is first saved.
 func GetConfigPath(appName string) (string, error) {
 	var configDir string
 
@@ -63,49 +99,45 @@ func GetConfigPath(appName string) (string, error) {
 		return "", fmt.Errorf("unsupported platform")
 	}
 
-	// Ensure the application-specific configuration directory exists.
-	appConfigDir := filepath.Join(configDir, appName)
-	if err := os.MkdirAll(appConfigDir, 0755); err != nil {
-		return "", err
-	}
-
-	return appConfigDir, nil
+	return filepath.Join(configDir, appName), nil
 }
 
-// InitConfig initializes the application configuration by loading it from a file.
-// It uses GetConfigPath to determine the correct path for the configuration file.
-// AppConfig and Pipeline are defined as before
-
 // InitConfig initializes the application configuration by loading it from a file,
-// or creates a new file with default configuration if it does not exist.
+// or creates a new file with default configuration if it does not exist. Files of older
+// schema versions are upgraded and files of newer versions are refused. In read-only mode
+// a missing file is not created and upgrades are not saved.
 func InitConfig() error {
 	var err error
 	once.Do(func() {
-		configPath, pathErr := GetConfigPath("floom-cli")
+		if _, _, projectErr := Project(); projectErr != nil {
+			err = projectErr
+			return
+		}
+
+		configFilePath, pathErr := ConfigFilePath()
 		if pathErr != nil {
 			err = fmt.Errorf("failed to get config path: %v", pathErr)
 			return
 		}
 
-		configFilePath := filepath.Join(configPath, "config.json")
+		// Parallel runs must not upgrade, restore or create the file at the same time
+		if !ReadOnly() {
+			unlock, lockErr := lockConfig(configFilePath)
+			if lockErr != nil {
+				err = lockErr
+				return
+			}
+			defer unlock()
+		}
 
 		// Check if the config file exists, if not, create it with the default configuration.
 		if _, openErr := os.Stat(configFilePath); os.IsNotExist(openErr) {
 			defaultConfig := AppConfig{
-				ConfigVersion: "1.0",
-			}
-
-			file, createErr := os.Create(configFilePath)
-			if createErr != nil {
-				err = fmt.Errorf("failed to create config file: %v", createErr)
-				return
+				SchemaVersion: CurrentSchemaVersion,
 			}
-			defer file.Close()
 
-			encoder := json.NewEncoder(file)
-			encoder.SetIndent("", "    ")
-			if encodeErr := encoder.Encode(defaultConfig); encodeErr != nil {
-				err = fmt.Errorf("failed to encode default config: %v", encodeErr)
+			if saveErr := defaultConfig.SaveConfig(); saveErr != nil {
+				err = fmt.Errorf("failed to create config file: %v", saveErr)
 				return
 			}
 
@@ -114,142 +146,311 @@ func InitConfig() error {
 			return // Config is initialized with default, no need to load from file.
 		}
 
-		// Config file exists, proceed to open and decode it.
-		file, openErr := os.Open(configFilePath)
-		if openErr != nil {
-			err = fmt.Errorf("failed to open config file: %v", openErr)
+		// Config file exists, proceed to read and decode it.
+		loaded, changed, loadErr := readConfigFile(configFilePath)
+		if loadErr != nil {
+			err = loadErr
 			return
 		}
-		defer file.Close()
+		appConfig = loaded
 
-		decoder := json.NewDecoder(file)
-		appConfig = &AppConfig{}
-		if decodeErr := decoder.Decode(appConfig); decodeErr != nil {
-			err = fmt.Errorf("failed to decode config file: %v", decodeErr)
-			return
+		if changed {
+			if saveErr := appConfig.SaveConfig(); saveErr != nil {
+				err = fmt.Errorf("failed to save upgraded config file: %v", saveErr)
+				return
+			}
+		}
+
+		// Files written by older versions may be readable by other users
+		if info, statErr := os.Stat(configFilePath); statErr == nil && info.Mode().Perm()&0077 != 0 && !ReadOnly() {
+			os.Chmod(configFilePath, 0600)
 		}
 	})
 
 	return err
 }
 
-func (c *AppConfig) SaveConfig() error {
-	configPath, err := GetConfigPath("floom-cli")
+// backupSuffix names the copy of the last good configuration, e.g. config.json.bak. It is
+// used when config.json is found damaged.
+const backupSuffix = ".bak"
+
+// readConfigFile reads and decodes a configuration file. A damaged file, e.g. one left empty
+// by an older version that crashed while saving, is restored from the last good backup and
+// kept as config.json.damaged. It reports whether the configuration was upgraded or restored
+// and should be saved.
+func readConfigFile(configFilePath string) (*AppConfig, bool, error) {
+	data, err := os.ReadFile(configFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to get config path: %v", err)
+		return nil, false, fmt.Errorf("failed to open config file: %v", err)
+	}
+
+	restored := false
+	if !json.Valid(data) {
+		backupPath := configFilePath + backupSuffix
+		backup, backupErr := os.ReadFile(backupPath)
+		if backupErr != nil || !json.Valid(backup) {
+			return nil, false, fmt.Errorf("config file %s is damaged and there is no valid backup at %s", configFilePath, backupPath)
+		}
+		if !ReadOnly() {
+			if err := writeFileAtomic(configFilePath+".damaged", data, 0600); err != nil {
+				return nil, false, fmt.Errorf("failed to keep damaged config file: %v", err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s is damaged, using the last good configuration from %s.\n", configFilePath, backupPath)
+		data, restored = backup, true
 	}
-	configFilePath := filepath.Join(configPath, "config.json")
 
-	file, err := os.Create(configFilePath)
+	config, upgraded, err := decodeConfig(configFilePath, data, !ReadOnly())
 	if err != nil {
-		return fmt.Errorf("failed to create/open config file: %v", err)
+		return nil, false, err
 	}
-	defer file.Close()
+	return config, upgraded || restored, nil
+}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "    ")
-	if err := encoder.Encode(c); err != nil {
-		return fmt.Errorf("failed to encode and save config: %v", err)
+// Update runs a read-modify-write cycle on the configuration under the config lock, so
+// parallel runs of the CLI do not lose each other's changes. The file is read again before
+// change is applied, and saved afterwards. If change fails, nothing is saved and c is left
+// unchanged.
+func (c *AppConfig) Update(change func(current *AppConfig) error) error {
+	if ReadOnly() {
+		return change(c)
 	}
 
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to get config path: %v", err)
+	}
+	unlock, err := lockConfig(configFilePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Another run may have saved the file since it was loaded
+	var current *AppConfig
+	if _, statErr := os.Stat(configFilePath); os.IsNotExist(statErr) {
+		current, err = c.clone()
+	} else {
+		current, _, err = readConfigFile(configFilePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := change(current); err != nil {
+		return err
+	}
+	if err := current.SaveConfig(); err != nil {
+		return err
+	}
+	*c = *current
 	return nil
 }
 
-func (c *AppConfig) AddOrUpdatePipeline(deploymentType, name, url string, port *int) {
-	if c.Deployments == nil {
-		c.Deployments = make(map[string]DeploymentConfiguration)
+// clone returns a deep copy of the configuration.
+func (c *AppConfig) clone() (*AppConfig, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %v", err)
 	}
-
-	deploymentConfig, exists := c.Deployments[deploymentType]
-	if !exists {
-		deploymentConfig = DeploymentConfiguration{
-			Credentials: DeploymentCredentials{}, // Initialize if necessary
-			Pipelines:   []PipelineConfiguration{},
-		}
+	clone := &AppConfig{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %v", err)
 	}
+	return clone, nil
+}
 
-	// Check for an existing pipeline and update if found
-	found := false
-	for i, pipeline := range deploymentConfig.Pipelines {
-		if pipeline.Name == name {
-			found = true
-			deploymentConfig.Pipelines[i] = PipelineConfiguration{Name: name, Url: url, Port: port}
-			break
-		}
+// SaveConfig writes the configuration to its file through a temporary file, so a crash never
+// leaves it partially written, and then updates the backup of the last good configuration.
+// In read-only mode nothing is written and changes only last for the current run. Callers
+// that read the configuration before changing it use Update instead.
+func (c *AppConfig) SaveConfig() error {
+	if ReadOnly() {
+		return nil
 	}
 
-	// If not found, append a new pipeline
-	if !found {
-		newPipeline := PipelineConfiguration{Name: name, Url: url, Port: port}
-		deploymentConfig.Pipelines = append(deploymentConfig.Pipelines, newPipeline)
+	configFilePath, err := ConfigFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to get config path: %v", err)
 	}
 
-	// Update the deployment config in the app config
-	c.Deployments[deploymentType] = deploymentConfig
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode and save config: %v", err)
+	}
 
-	// Save the updated configuration
-	if err := c.SaveConfig(); err != nil {
-		fmt.Printf("Failed to save config: %v\n", err)
+	data = append(data, '\n')
+	if err := writeFileAtomic(configFilePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
 	}
+	if err := writeFileAtomic(configFilePath+backupSuffix, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config backup: %v", err)
+	}
+
+	return nil
+}
+
+// AddOrUpdatePipeline records a deployed pipeline in the active profile. Parallel deploys
+// each add their pipelines, as the configuration is updated under the config lock.
+func (c *AppConfig) AddOrUpdatePipeline(deploymentType, name, url string, port *int) error {
+	profileName := ActiveProfileName()
+	return c.Update(func(current *AppConfig) error {
+		deployments := current.Profile(profileName).Deployments
+
+		deploymentConfig, exists := deployments[deploymentType]
+		if !exists {
+			deploymentConfig = DeploymentConfiguration{
+				Credentials: DeploymentCredentials{}, // Initialize if necessary
+				Pipelines:   []PipelineConfiguration{},
+			}
+		}
+
+		// Check for an existing pipeline and update if found
+		found := false
+		for i, pipeline := range deploymentConfig.Pipelines {
+			if pipeline.Name == name {
+				found = true
+				deploymentConfig.Pipelines[i] = PipelineConfiguration{Name: name, Url: url, Port: port}
+				break
+			}
+		}
+
+		// If not found, append a new pipeline
+		if !found {
+			newPipeline := PipelineConfiguration{Name: name, Url: url, Port: port}
+			deploymentConfig.Pipelines = append(deploymentConfig.Pipelines, newPipeline)
+		}
+
+		// Update the deployment config in the app config
+		deployments[deploymentType] = deploymentConfig
+		return nil
+	})
+}
+
+// GetNetworkConfigForDeployment returns the network settings of a deployment of the active
+// profile, or nil.
+func GetNetworkConfigForDeployment(deploymentType string) *NetworkConfiguration {
+	return ActiveProfile().Deployments[deploymentType].Network
 }
 
 func DeploymentConfigExists(deploymentType string) bool {
-	_, exists := GetConfig().Deployments[deploymentType]
+	_, exists := ActiveProfile().Deployments[deploymentType]
 	return exists
 }
 
-// UpdateUserConfig function to update apiKey, username, and nickname in AppConfig.
+// UpdateUserConfig function to update apiKey, username, and nickname of a deployment of
+// the active profile.
 func UpdateUserConfig(apiKey, username, nickname, deploymentType string) error {
 	if appConfig == nil {
 		return fmt.Errorf("config is not initialized")
 	}
+	profileName := ActiveProfileName()
 
-	// Check if the Deployments map is initialized; if not, initialize it.
-	if appConfig.Deployments == nil {
-		appConfig.Deployments = make(map[string]DeploymentConfiguration)
+	// The passphrase of the credential store must not be asked for under the config lock
+	if err := appConfig.prepareCredentialStore(); err != nil {
+		return err
 	}
 
-	// Check if the specified deployment exists; if not, initialize it.
-	if _, exists := appConfig.Deployments[deploymentType]; !exists {
-		appConfig.Deployments[deploymentType] = DeploymentConfiguration{
-			Credentials: DeploymentCredentials{},   // Initialize with empty credentials
-			Pipelines:   []PipelineConfiguration{}, // Initialize with an empty slice
+	var previous, credentials DeploymentCredentials
+	err := appConfig.Update(func(current *AppConfig) error {
+		deployments := current.Profile(profileName).Deployments
+
+		// Check if the specified deployment exists; if not, initialize it.
+		if _, exists := deployments[deploymentType]; !exists {
+			deployments[deploymentType] = DeploymentConfiguration{
+				Credentials: DeploymentCredentials{},   // Initialize with empty credentials
+				Pipelines:   []PipelineConfiguration{}, // Initialize with an empty slice
+			}
+		}
+
+		// Update the credentials for the specific deployment type, the API key goes to the
+		// credential store.
+		deploymentConfig := deployments[deploymentType]
+		previous = deploymentConfig.Credentials
+		var err error
+		credentials, err = current.storeApiKey(profileName, deploymentType, apiKey, DeploymentCredentials{
+			Username: username,
+			Nickname: nickname,
+		})
+		if err != nil {
+			return err
 		}
+		deploymentConfig.Credentials = credentials
+
+		// Important: Update the map entry with the modified deploymentConfig
+		deployments[deploymentType] = deploymentConfig
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Update the credentials for the specific deployment type.
-	deploymentConfig := appConfig.Deployments[deploymentType]
-	deploymentConfig.Credentials = DeploymentCredentials{
-		ApiKey:   apiKey,
-		Username: username,
-		Nickname: nickname,
+	// A key of another backend is not referenced any more
+	if previous.ApiKeyRef != "" && previous.ApiKeyRef != credentials.ApiKeyRef {
+		return appConfig.deleteApiKey(previous)
 	}
+	return nil
+}
 
-	// Important: Update the map entry with the modified deploymentConfig
-	appConfig.Deployments[deploymentType] = deploymentConfig
+// RemoveCredentials clears the credentials of a deployment of the active profile and saves
+// the configuration. Its pipelines and network settings are kept. It reports whether there
+// were credentials.
+func RemoveCredentials(deploymentType string) (bool, error) {
+	if appConfig == nil {
+		return false, fmt.Errorf("config is not initialized")
+	}
+	profileName := ActiveProfileName()
 
-	// Use the existing SaveConfig function to save the updated AppConfig.
-	return appConfig.SaveConfig()
+	// Drop the reference first, a key left in the store is harmless
+	var previous DeploymentCredentials
+	err := appConfig.Update(func(current *AppConfig) error {
+		deployments := current.Profile(profileName).Deployments
+
+		deploymentConfig, exists := deployments[deploymentType]
+		if !exists {
+			return nil
+		}
+		previous = deploymentConfig.Credentials
+		deploymentConfig.Credentials = DeploymentCredentials{}
+		deployments[deploymentType] = deploymentConfig
+		return nil
+	})
+	if err != nil || previous == (DeploymentCredentials{}) {
+		return false, err
+	}
+
+	if err := appConfig.deleteApiKey(previous); err != nil {
+		return true, fmt.Errorf("credentials removed, but the API key could not be deleted from the credential store: %w", err)
+	}
+	return true, nil
 }
 
-// GetApiKeyForDeployment returns the API key for a given deployment type.
+// GetApiKeyForDeployment returns the API key for a given deployment type of the active
+// profile. A key given with FLOOM_API_KEY takes precedence over the stored one.
 func GetApiKeyForDeployment(deploymentType string) (string, error) {
-	appConfig := GetConfig() // Assuming GetConfig() fetches the current AppConfig instance.
-
 	if deploymentType == "local" {
 		return "", nil
 	}
 
-	// Check if the deployment exists in the appConfig
-	deploymentConfig, exists := appConfig.Deployments[deploymentType]
+	if apiKey := apiKeyFromEnvironment(); apiKey != "" {
+		return apiKey, nil
+	}
+
+	return GetStoredApiKey(deploymentType)
+}
+
+// GetStoredApiKey returns the API key stored for a deployment type of the active profile,
+// ignoring FLOOM_API_KEY.
+func GetStoredApiKey(deploymentType string) (string, error) {
+	// Check if the deployment exists in the active profile
+	deploymentConfig, exists := ActiveProfile().Deployments[deploymentType]
 	if !exists {
-		return "", fmt.Errorf("deployment type '%s' not found in configuration", deploymentType)
+		return "", fmt.Errorf("deployment type '%s' not found in profile '%s'", deploymentType, ActiveProfileName())
 	}
 
-	apiKey := deploymentConfig.Credentials.ApiKey
-	if apiKey == "" {
+	if !deploymentConfig.Credentials.HasApiKey() {
 		return "", fmt.Errorf("API key for deployment type '%s' is missing", deploymentType)
 	}
 
-	return apiKey, nil
+	return GetConfig().resolveApiKey(deploymentConfig.Credentials)
 }
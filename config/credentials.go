@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Credential store backends, selected by "credential_store.backend" in config.json.
+const (
+	// BackendFile keeps API keys in a passphrase-encrypted file next to config.json. It is
+	// the default.
+	BackendFile = "file"
+	// BackendHelper hands API keys to an external credential helper command.
+	BackendHelper = "helper"
+	// BackendPlaintext keeps API keys in config.json, as older versions of the CLI did.
+	BackendPlaintext = "plaintext"
+)
+
+// credentialsFileName is the encrypted file of the file backend.
+const credentialsFileName = "credentials.enc"
+
+// CredentialStoreConfiguration selects where API keys are stored. Helper is the command
+// line of the credential helper for the "helper" backend.
+type CredentialStoreConfiguration struct {
+	Backend string `json:"backend"`
+	Helper  string `json:"helper,omitempty"`
+}
+
+// CredentialStore keeps secrets outside of config.json. Secrets are addressed by a
+// reference that is stored in config.json instead of the secret itself.
+type CredentialStore interface {
+	Get(ref string) (string, error)
+	Set(ref, secret string) error
+	Delete(ref string) error
+}
+
+// credentialStorePreparer is implemented by stores that need input from the user, such as
+// a passphrase, before a secret can be set.
+type credentialStorePreparer interface {
+	Prepare() error
+}
+
+// PassphrasePrompt asks the user for the passphrase of the encrypted credential file.
+// confirm is set when the file is created and the passphrase should be entered twice.
+// The CLI sets it to an interactive prompt; if it is nil, only the
+// FLOOM_CREDENTIALS_PASSPHRASE environment variable is used.
+var PassphrasePrompt func(confirm bool) (string, error)
+
+var (
+	credentialStores   = map[string]CredentialStore{}
+	credentialStoresMu sync.Mutex
+)
+
+// HasApiKey reports whether an API key is stored for the deployment, in config.json or in
+// a credential store.
+func (c DeploymentCredentials) HasApiKey() bool {
+	return c.ApiKey != "" || c.ApiKeyRef != ""
+}
+
+// CredentialBackend returns the configured credential backend, "file" by default.
+func (c *AppConfig) CredentialBackend() string {
+	if c.CredentialStore == nil || c.CredentialStore.Backend == "" {
+		return BackendFile
+	}
+	return c.CredentialStore.Backend
+}
+
+// credentialStore returns the store of a backend. Stores are created once per run, so the
+// passphrase of the file backend is asked for at most once.
+func (c *AppConfig) credentialStore(backend string) (CredentialStore, error) {
+	credentialStoresMu.Lock()
+	defer credentialStoresMu.Unlock()
+
+	if store, ok := credentialStores[backend]; ok {
+		return store, nil
+	}
+
+	var store CredentialStore
+	switch backend {
+	case BackendFile:
+		// The file is kept next to the configuration file in use
+		configFilePath, err := ConfigFilePath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config path: %v", err)
+		}
+		store = newFileCredentialStore(filepath.Join(filepath.Dir(configFilePath), credentialsFileName))
+	case BackendHelper:
+		if c.CredentialStore == nil || strings.TrimSpace(c.CredentialStore.Helper) == "" {
+			return nil, fmt.Errorf("credential_store.helper must be set for the helper backend")
+		}
+		store = &helperCredentialStore{command: c.CredentialStore.Helper}
+	default:
+		return nil, fmt.Errorf("unknown credential store backend '%s'", backend)
+	}
+
+	credentialStores[backend] = store
+	return store, nil
+}
+
+// credentialRef builds the reference of a deployment's API key, e.g. "file:cloud" or
+// "file:team/cloud" in the "team" profile. The backend is part of the reference, so keys
+// stay readable after the backend is changed.
+func credentialRef(backend, profile, deploymentType string) string {
+	if profile == DefaultProfileName {
+		return backend + ":" + deploymentType
+	}
+	return backend + ":" + profile + "/" + deploymentType
+}
+
+func parseCredentialRef(ref string) (backend string, err error) {
+	backend, _, found := strings.Cut(ref, ":")
+	if !found || backend == "" {
+		return "", fmt.Errorf("invalid credential reference '%s'", ref)
+	}
+	return backend, nil
+}
+
+// resolveApiKey returns the API key of stored credentials.
+func (c *AppConfig) resolveApiKey(credentials DeploymentCredentials) (string, error) {
+	if credentials.ApiKeyRef == "" {
+		return credentials.ApiKey, nil
+	}
+
+	backend, err := parseCredentialRef(credentials.ApiKeyRef)
+	if err != nil {
+		return "", err
+	}
+	store, err := c.credentialStore(backend)
+	if err != nil {
+		return "", err
+	}
+	apiKey, err := store.Get(credentials.ApiKeyRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key from the %s credential store: %w", backend, err)
+	}
+	return apiKey, nil
+}
+
+// storeApiKey saves the API key of a deployment of a profile with the configured backend
+// and returns the credentials to keep in config.json. In read-only mode the key is only kept
+// in memory.
+func (c *AppConfig) storeApiKey(profile, deploymentType, apiKey string, credentials DeploymentCredentials) (DeploymentCredentials, error) {
+	backend := c.CredentialBackend()
+	if backend == BackendPlaintext || ReadOnly() {
+		credentials.ApiKey, credentials.ApiKeyRef = apiKey, ""
+		return credentials, nil
+	}
+
+	store, err := c.credentialStore(backend)
+	if err != nil {
+		return credentials, err
+	}
+	ref := credentialRef(backend, profile, deploymentType)
+	if err := store.Set(ref, apiKey); err != nil {
+		return credentials, fmt.Errorf("failed to save API key to the %s credential store: %w", backend, err)
+	}
+	credentials.ApiKey, credentials.ApiKeyRef = "", ref
+	return credentials, nil
+}
+
+// PrepareCredentialStore makes sure an API key can be stored with the configured backend,
+// asking for the passphrase of the encrypted file now if needed. Commands that register a
+// user call it first, so a locked store fails before the server issues a key that would
+// be lost.
+func PrepareCredentialStore() error {
+	if appConfig == nil {
+		return fmt.Errorf("config is not initialized")
+	}
+	return appConfig.prepareCredentialStore()
+}
+
+// prepareCredentialStore unlocks the store of the configured backend, so storeApiKey does
+// not ask for a passphrase. It is called before the config lock is taken.
+func (c *AppConfig) prepareCredentialStore() error {
+	backend := c.CredentialBackend()
+	if backend == BackendPlaintext || ReadOnly() {
+		return nil
+	}
+
+	store, err := c.credentialStore(backend)
+	if err != nil {
+		return err
+	}
+	if preparer, ok := store.(credentialStorePreparer); ok {
+		if err := preparer.Prepare(); err != nil {
+			return fmt.Errorf("the %s credential store cannot save API keys: %w", backend, err)
+		}
+	}
+	return nil
+}
+
+// deleteApiKey removes the API key of credentials from its credential store, if any. Nothing
+// is deleted in read-only mode.
+func (c *AppConfig) deleteApiKey(credentials DeploymentCredentials) error {
+	if credentials.ApiKeyRef == "" || ReadOnly() {
+		return nil
+	}
+
+	backend, err := parseCredentialRef(credentials.ApiKeyRef)
+	if err != nil {
+		return err
+	}
+	store, err := c.credentialStore(backend)
+	if err != nil {
+		return err
+	}
+	return store.Delete(credentials.ApiKeyRef)
+}
+
+// credentialRefs returns the references of all API keys kept in credential stores.
+func (c *AppConfig) credentialRefs() map[string]bool {
+	refs := map[string]bool{}
+	for _, profile := range c.Profiles {
+		if profile == nil {
+			continue
+		}
+		for _, deployment := range profile.Deployments {
+			if deployment.Credentials.ApiKeyRef != "" {
+				refs[deployment.Credentials.ApiKeyRef] = true
+			}
+		}
+	}
+	return refs
+}
+
+// PlaintextApiKeys returns the deployments of all profiles whose API key is still kept in
+// config.json, as "profile/deployment".
+func PlaintextApiKeys() []string {
+	var deployments []string
+	for name, profile := range GetConfig().Profiles {
+		if profile == nil {
+			continue
+		}
+		for deploymentType, deployment := range profile.Deployments {
+			if deployment.Credentials.ApiKey != "" {
+				deployments = append(deployments, name+"/"+deploymentType)
+			}
+		}
+	}
+	sort.Strings(deployments)
+	return deployments
+}
+
+// MigrateCredentials moves every API key of all profiles to the configured credential
+// store, both plaintext keys of config.json and keys of other backends, and saves the
+// configuration. It returns the migrated deployments as "profile/deployment".
+func MigrateCredentials() ([]string, error) {
+	if appConfig == nil {
+		return nil, fmt.Errorf("config is not initialized")
+	}
+
+	var migrated []string
+	for _, name := range ProfileNames() {
+		profileMigrated, err := appConfig.migrateProfileCredentials(name)
+		migrated = append(migrated, profileMigrated...)
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+func (c *AppConfig) migrateProfileCredentials(name string) ([]string, error) {
+	profile := c.Profile(name)
+	deploymentTypes := make([]string, 0, len(profile.Deployments))
+	for deploymentType := range profile.Deployments {
+		deploymentTypes = append(deploymentTypes, deploymentType)
+	}
+	sort.Strings(deploymentTypes)
+
+	backend := c.CredentialBackend()
+	var migrated []string
+	for _, deploymentType := range deploymentTypes {
+		credentials := c.Profile(name).Deployments[deploymentType].Credentials
+		if !credentials.HasApiKey() || credentials.ApiKeyRef == credentialRef(backend, name, deploymentType) {
+			continue
+		}
+		if backend == BackendPlaintext && credentials.ApiKeyRef == "" {
+			continue
+		}
+
+		// Passphrases are asked for before the config is locked
+		apiKey, err := c.resolveApiKey(credentials)
+		if err != nil {
+			return migrated, fmt.Errorf("deployment '%s' of profile '%s': %w", deploymentType, name, err)
+		}
+		if err := c.prepareCredentialStore(); err != nil {
+			return migrated, err
+		}
+
+		// Save before the old copy is removed, so the key is never lost
+		err = c.Update(func(current *AppConfig) error {
+			deployments := current.Profile(name).Deployments
+			deployment, exists := deployments[deploymentType]
+			if !exists || deployment.Credentials != credentials {
+				return fmt.Errorf("deployment '%s' of profile '%s' changed during the migration, run it again", deploymentType, name)
+			}
+			var err error
+			if deployment.Credentials, err = current.storeApiKey(name, deploymentType, apiKey, credentials); err != nil {
+				return fmt.Errorf("deployment '%s' of profile '%s': %w", deploymentType, name, err)
+			}
+			deployments[deploymentType] = deployment
+			return nil
+		})
+		if err != nil {
+			return migrated, err
+		}
+		if err := c.deleteApiKey(credentials); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not remove the old copy of the API key of '%s': %v\n", deploymentType, err)
+		}
+		migrated = append(migrated, name+"/"+deploymentType)
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Environment variables for CI and other headless runs. Each of them overrides the project
+// file and the user configuration; command line flags override them.
+const (
+	// configEnvVar is the path of the configuration file, unless --config is given.
+	configEnvVar = "FLOOM_CONFIG"
+	// ApiKeyEnvVar holds an API key that is used instead of the stored one.
+	ApiKeyEnvVar = "FLOOM_API_KEY"
+	// endpointEnvVar is the API endpoint URL used for the deployment target of a run.
+	endpointEnvVar = "FLOOM_ENDPOINT"
+	// targetEnvVar is the deployment target of commands run without one.
+	targetEnvVar = "FLOOM_TARGET"
+	// readOnlyEnvVar enables the read-only mode, unless --read-only is given.
+	readOnlyEnvVar = "FLOOM_READ_ONLY"
+)
+
+// ProjectFileName is the project file, looked up in the working directory and its parents.
+// It is meant to be committed, so it never holds credentials.
+const ProjectFileName = ".floom.json"
+
+// ProjectConfiguration holds the defaults of a project file. Endpoint is only read to warn
+// that it is ignored: a project file from a cloned repository must not send the stored API
+// keys to another server.
+type ProjectConfiguration struct {
+	Profile  string `json:"profile,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+var (
+	// configFileFlag is the configuration file given on the command line, if any.
+	configFileFlag string
+	// readOnlyFlag is set by --read-only.
+	readOnlyFlag bool
+)
+
+var (
+	project     *ProjectConfiguration
+	projectPath string
+	projectErr  error
+	projectOnce sync.Once
+)
+
+// SetConfigFile selects the configuration file for this run, e.g. from a --config flag. An
+// empty path falls back to FLOOM_CONFIG and then to the user configuration directory.
+func SetConfigFile(path string) {
+	configFileFlag = path
+}
+
+// SetReadOnly enables the read-only mode for this run, e.g. from a --read-only flag.
+// Without it, FLOOM_READ_ONLY decides.
+func SetReadOnly(readOnly bool) {
+	readOnlyFlag = readOnly
+}
+
+// ReadOnly reports whether the configuration and the credential store must not be written.
+// Changes are then only kept in memory for the rest of the run.
+func ReadOnly() bool {
+	if readOnlyFlag {
+		return true
+	}
+	readOnly, _ := strconv.ParseBool(os.Getenv(readOnlyEnvVar))
+	return readOnly
+}
+
+// ConfigFilePath returns the path of the configuration file: the one given with --config,
+// then FLOOM_CONFIG, then config.json in the user configuration directory.
+func ConfigFilePath() (string, error) {
+	if configFileFlag != "" {
+		return configFileFlag, nil
+	}
+	if path := os.Getenv(configEnvVar); path != "" {
+		return path, nil
+	}
+
+	configPath, err := GetConfigPath("floom-cli")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configPath, "config.json"), nil
+}
+
+// Project returns the project file found in the working directory or one of its parents,
+// and its path. Both are empty when there is none.
+func Project() (*ProjectConfiguration, string, error) {
+	projectOnce.Do(func() {
+		dir, err := os.Getwd()
+		if err != nil {
+			projectErr = err
+			return
+		}
+
+		for {
+			path := filepath.Join(dir, ProjectFileName)
+			data, err := os.ReadFile(path)
+			if err == nil {
+				project, projectPath = &ProjectConfiguration{}, path
+				if err := json.Unmarshal(data, project); err != nil {
+					projectErr = fmt.Errorf("failed to decode project file %s: %v", path, err)
+				} else if project.Endpoint != "" {
+					fmt.Fprintf(os.Stderr, "Warning: ignoring \"endpoint\" in %s, set %s to use another server.\n", path, endpointEnvVar)
+				}
+				return
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				projectErr = fmt.Errorf("failed to read project file %s: %v", path, err)
+				return
+			}
+
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return
+			}
+			dir = parent
+		}
+	})
+	return project, projectPath, projectErr
+}
+
+// projectSetting returns a setting of the project file, or "" if there is none.
+func projectSetting(setting func(*ProjectConfiguration) string) string {
+	if project, _, err := Project(); err == nil && project != nil {
+		return setting(project)
+	}
+	return ""
+}
+
+// DefaultTarget returns the deployment target of commands run without one: FLOOM_TARGET,
+// then the target of the project file. It is "" if neither is set.
+func DefaultTarget() string {
+	if target := os.Getenv(targetEnvVar); target != "" {
+		return target
+	}
+	return projectSetting(func(p *ProjectConfiguration) string { return p.Target })
+}
+
+// Endpoint returns the API endpoint URL that replaces the URL of the deployment target,
+// given with FLOOM_ENDPOINT. It is "" if it is not set.
+func Endpoint() string {
+	return os.Getenv(endpointEnvVar)
+}
+
+// apiKeyFromEnvironment returns the API key given with FLOOM_API_KEY, or "".
+func apiKeyFromEnvironment() string {
+	return os.Getenv(ApiKeyEnvVar)
+}
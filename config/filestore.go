@@ -0,0 +1,273 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"os"
+	"path/filepath"
+)
+
+// passphraseEnvVar holds the passphrase of the encrypted credential file, for scripts.
+const passphraseEnvVar = "FLOOM_CREDENTIALS_PASSPHRASE"
+
+// scrypt parameters for new credential files. They are stored in the file, so they can be
+// raised later without breaking existing files.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// encryptedCredentials is the on-disk format of the file backend: the secrets map is
+// encrypted with AES-256-GCM under a key derived from the passphrase with scrypt.
+type encryptedCredentials struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// fileCredentialStore is the passphrase-encrypted credential file. The file is decrypted
+// once and the secrets are kept in memory for the rest of the run. Changes read the file
+// again under a lock, so parallel runs of the CLI do not drop each other's secrets.
+type fileCredentialStore struct {
+	path       string
+	passphrase string
+	secrets    map[string]string
+}
+
+func newFileCredentialStore(path string) *fileCredentialStore {
+	return &fileCredentialStore{path: path}
+}
+
+func (s *fileCredentialStore) Get(ref string) (string, error) {
+	if err := s.unlock(); err != nil {
+		return "", err
+	}
+	secret, ok := s.secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret stored for '%s'", ref)
+	}
+	return secret, nil
+}
+
+func (s *fileCredentialStore) Set(ref, secret string) error {
+	if err := s.Prepare(); err != nil {
+		return err
+	}
+	return s.update(func(secrets map[string]string) bool {
+		secrets[ref] = secret
+		return true
+	})
+}
+
+// Prepare unlocks the file, or chooses the passphrase of a new file, so secrets can be set
+// without asking for it.
+func (s *fileCredentialStore) Prepare() error {
+	if err := s.unlock(); err != nil {
+		return err
+	}
+	if s.passphrase == "" {
+		// The file does not exist yet, choose its passphrase
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			return err
+		}
+		s.passphrase = passphrase
+	}
+	return nil
+}
+
+func (s *fileCredentialStore) Delete(ref string) error {
+	return s.update(func(secrets map[string]string) bool {
+		if _, ok := secrets[ref]; !ok {
+			return false
+		}
+		delete(secrets, ref)
+		return true
+	})
+}
+
+// update changes the secrets and saves them if change reports a change. The passphrase is
+// asked for first, then the file is read again and written under its lock.
+func (s *fileCredentialStore) update(change func(secrets map[string]string) bool) error {
+	if err := s.unlock(); err != nil {
+		return err
+	}
+
+	release, err := lockPath(s.path, "credential file")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	secrets, err := s.read()
+	if err != nil {
+		return err
+	}
+	s.secrets = secrets
+	if !change(secrets) {
+		return nil
+	}
+	return s.save()
+}
+
+// unlock decrypts the credential file once per run. A missing file is an empty store, its
+// passphrase is chosen when the first secret is set.
+func (s *fileCredentialStore) unlock() error {
+	if s.secrets != nil {
+		return nil
+	}
+	secrets, err := s.read()
+	if err != nil {
+		return err
+	}
+	s.secrets = secrets
+	return nil
+}
+
+// read reads and decrypts the credential file, asking for the passphrase unless it is
+// known.
+func (s *fileCredentialStore) read() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential file: %v", err)
+	}
+
+	var file encryptedCredentials
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credential file %s: %v", s.path, err)
+	}
+	if file.Version != 1 || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported credential file format in %s", s.path)
+	}
+
+	passphrase := s.passphrase
+	if passphrase == "" {
+		if passphrase, err = readPassphrase(false); err != nil {
+			return nil, err
+		}
+	}
+
+	gcm, err := newCredentialCipher(passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted credential file %s", s.path)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse credential file %s: %v", s.path, err)
+	}
+	s.passphrase = passphrase
+	return secrets, nil
+}
+
+// save encrypts the secrets with a fresh salt and nonce and replaces the file.
+func (s *fileCredentialStore) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedCredentials{Version: 1, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP}
+	file.Salt = make([]byte, 16)
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+
+	gcm, err := newCredentialCipher(s.passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0600)
+}
+
+func newCredentialCipher(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive credential key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readPassphrase returns the passphrase from the environment or the interactive prompt.
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	if PassphrasePrompt == nil {
+		return "", fmt.Errorf("the credential store is locked, set %s", passphraseEnvVar)
+	}
+
+	passphrase, err := PassphrasePrompt(confirm)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase is empty")
+	}
+	return passphrase, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it
+// over path, so readers never see a partially written file. A missing directory is created
+// for the user only, as it holds credentials.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(perm); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFileCredentialStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		setPassphrase string
+		getPassphrase string
+		wantErr       string
+	}{
+		{name: "same passphrase", setPassphrase: "correct horse", getPassphrase: "correct horse"},
+		{name: "wrong passphrase", setPassphrase: "correct horse", getPassphrase: "battery staple", wantErr: "wrong passphrase"},
+		{name: "no passphrase", setPassphrase: "correct horse", wantErr: "credential store is locked"},
+	}
+
+	PassphrasePrompt = nil
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), credentialsFileName)
+
+			t.Setenv(passphraseEnvVar, test.setPassphrase)
+			if err := newFileCredentialStore(path).Set("file:cloud", "secret-key"); err != nil {
+				t.Fatalf("failed to set secret: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read credential file: %v", err)
+			}
+			if strings.Contains(string(data), "secret-key") {
+				t.Fatalf("credential file contains the secret in plaintext")
+			}
+
+			t.Setenv(passphraseEnvVar, test.getPassphrase)
+			secret, err := newFileCredentialStore(path).Get("file:cloud")
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Get error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get secret: %v", err)
+			}
+			if secret != "secret-key" {
+				t.Errorf("Get = %q, want %q", secret, "secret-key")
+			}
+		})
+	}
+}
+
+func TestFileCredentialStorePrepare(t *testing.T) {
+	PassphrasePrompt = nil
+	path := filepath.Join(t.TempDir(), credentialsFileName)
+
+	t.Setenv(passphraseEnvVar, "")
+	if err := newFileCredentialStore(path).Prepare(); err == nil {
+		t.Fatalf("Prepare succeeded for a new file without a passphrase")
+	}
+
+	t.Setenv(passphraseEnvVar, "correct horse")
+	store := newFileCredentialStore(path)
+	if err := store.Prepare(); err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	// Once prepared, the store needs no passphrase to set secrets
+	t.Setenv(passphraseEnvVar, "")
+	if err := store.Set("file:cloud", "secret-key"); err != nil {
+		t.Fatalf("Set after Prepare failed: %v", err)
+	}
+}
+
+func TestFileCredentialStoreSharedFile(t *testing.T) {
+	PassphrasePrompt = nil
+	t.Setenv(passphraseEnvVar, "correct horse")
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, path string)
+		want   map[string]string
+	}{
+		{
+			// The first store decrypted the file before the second one changed it
+			name: "stale store",
+			change: func(t *testing.T, path string) {
+				first, second := newFileCredentialStore(path), newFileCredentialStore(path)
+				for _, store := range []*fileCredentialStore{first, second} {
+					if err := store.Prepare(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := second.Set("file:cloud", "cloud-key"); err != nil {
+					t.Fatal(err)
+				}
+				if err := first.Set("file:staging", "staging-key"); err != nil {
+					t.Fatal(err)
+				}
+				if err := second.Delete("file:old"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string]string{"file:cloud": "cloud-key", "file:staging": "staging-key"},
+		},
+		{
+			name: "parallel logins",
+			change: func(t *testing.T, path string) {
+				var wg sync.WaitGroup
+				for i := 0; i < 4; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						if err := newFileCredentialStore(path).Set(fmt.Sprintf("file:%d", i), fmt.Sprintf("key-%d", i)); err != nil {
+							t.Error(err)
+						}
+					}(i)
+				}
+				wg.Wait()
+			},
+			want: map[string]string{"file:0": "key-0", "file:1": "key-1", "file:2": "key-2", "file:3": "key-3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), credentialsFileName)
+			test.change(t, path)
+
+			secrets, err := newFileCredentialStore(path).read()
+			if err != nil {
+				t.Fatalf("failed to read credential file: %v", err)
+			}
+			if !reflect.DeepEqual(secrets, test.want) {
+				t.Errorf("secrets = %v, want %v", secrets, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// helperCredentialStore delegates secrets to an external credential helper, so teams can
+// plug in their own secret manager. The helper is run with the action as its last
+// argument and a JSON request on stdin:
+//
+//	<helper> get    {"ref": "helper:cloud"}                  -> prints {"secret": "..."}
+//	<helper> store  {"ref": "helper:cloud", "secret": "..."}
+//	<helper> erase  {"ref": "helper:cloud"}
+//
+// A non-zero exit status is a failure, the helper's stderr is used as the error message.
+type helperCredentialStore struct {
+	command string
+}
+
+type helperRequest struct {
+	Ref    string `json:"ref"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type helperResponse struct {
+	Secret string `json:"secret"`
+}
+
+func (s *helperCredentialStore) Get(ref string) (string, error) {
+	output, err := s.run("get", helperRequest{Ref: ref})
+	if err != nil {
+		return "", err
+	}
+
+	var response helperResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", fmt.Errorf("invalid response from credential helper: %v", err)
+	}
+	if response.Secret == "" {
+		return "", fmt.Errorf("credential helper has no secret for '%s'", ref)
+	}
+	return response.Secret, nil
+}
+
+func (s *helperCredentialStore) Set(ref, secret string) error {
+	_, err := s.run("store", helperRequest{Ref: ref, Secret: secret})
+	return err
+}
+
+func (s *helperCredentialStore) Delete(ref string) error {
+	_, err := s.run("erase", helperRequest{Ref: ref})
+	return err
+}
+
+func (s *helperCredentialStore) run(action string, request helperRequest) ([]byte, error) {
+	args, err := splitCommandLine(s.command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential helper: %v", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no credential helper configured")
+	}
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	helper := exec.Command(args[0], append(args[1:], action)...)
+	helper.Stdin = bytes.NewReader(input)
+	helper.Stdout = &stdout
+	helper.Stderr = &stderr
+
+	if err := helper.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("credential helper %s failed: %s", action, message)
+		}
+		return nil, fmt.Errorf("credential helper %s failed: %v", action, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// splitCommandLine splits the helper command line into arguments at spaces. Single or double
+// quotes keep spaces in an argument, e.g. "C:\Program Files\helper.exe" --vault team.
+// Backslashes are literal, so Windows paths need no escaping.
+func splitCommandLine(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArgument := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArgument = r, true
+		case r == ' ' || r == '\t':
+			if inArgument {
+				args = append(args, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		default:
+			current.WriteRune(r)
+			inArgument = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in '%s'", quote, command)
+	}
+	if inArgument {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
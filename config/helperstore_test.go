@@ -0,0 +1,34 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		wantErr bool
+	}{
+		{command: "my-floom-helper", args: []string{"my-floom-helper"}},
+		{command: "  helper  --vault   team ", args: []string{"helper", "--vault", "team"}},
+		{command: `"/opt/my tools/helper" --vault team`, args: []string{"/opt/my tools/helper", "--vault", "team"}},
+		{command: `'C:\Program Files\helper.exe' get`, args: []string{`C:\Program Files\helper.exe`, "get"}},
+		{command: `helper --name "it's"`, args: []string{"helper", "--name", "it's"}},
+		{command: `helper ""`, args: []string{"helper", ""}},
+		{command: `"/opt/my tools/helper`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			args, err := splitCommandLine(test.command)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("splitCommandLine(%q) error = %v, want error %v", test.command, err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(args, test.args) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", test.command, args, test.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Settings are addressed with dotted keys into the JSON form of config.json, e.g.
+// "credential_store.backend" or "profiles.team.deployments.cloud.network.timeout". Keys
+// starting with "deployments" address the deployments of the active profile. Segments that
+// contain dots, such as custom endpoint URLs, are written in brackets:
+// "deployments[https://floom.example.com].credentials.username".
+
+// MaskedValue replaces secret values in output.
+const MaskedValue = "********"
+
+// secretKeys are the settings whose values are masked in output.
+var secretKeys = map[string]bool{"api_key": true}
+
+// Setting is a single configuration value and its dotted key.
+type Setting struct {
+	Key   string      `json:"key" yaml:"key"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+// parseKey splits a dotted key into its segments, resolving the "deployments" shortcut.
+func parseKey(key string) ([]string, error) {
+	if key == "" || strings.HasSuffix(key, ".") {
+		return nil, fmt.Errorf("invalid key '%s'", key)
+	}
+
+	var segments []string
+	for rest := key; rest != ""; {
+		var segment string
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key '%s': missing ']'", key)
+			}
+			segment, rest = rest[1:end], rest[end+1:]
+			if rest != "" && rest[0] != '.' && rest[0] != '[' {
+				return nil, fmt.Errorf("invalid key '%s': expected '.' after ']'", key)
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segment, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimPrefix(rest, ".")
+
+		if segment == "" {
+			return nil, fmt.Errorf("invalid key '%s': empty segment", key)
+		}
+		segments = append(segments, segment)
+	}
+
+	if segments[0] == "deployments" {
+		segments = append([]string{"profiles", ActiveProfileName()}, segments...)
+	}
+	return segments, nil
+}
+
+// joinKey appends a segment to a dotted key, in brackets if it contains dots.
+func joinKey(key, segment string) string {
+	if strings.ContainsAny(segment, ".[]") {
+		return key + "[" + segment + "]"
+	}
+	if key == "" {
+		return segment
+	}
+	return key + "." + segment
+}
+
+// tree returns the JSON form of the configuration as generic values.
+func (c *AppConfig) tree() (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %v", err)
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %v", err)
+	}
+	return tree, nil
+}
+
+// listIndex parses a segment as an index of list.
+func listIndex(list []interface{}, segment string) (int, error) {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 || index >= len(list) {
+		return 0, fmt.Errorf("'%s' is not an index of a list with %d entries", segment, len(list))
+	}
+	return index, nil
+}
+
+func lookupPath(node interface{}, segments []string) (interface{}, bool) {
+	for _, segment := range segments {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[segment]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case []interface{}:
+			index, err := listIndex(n, segment)
+			if err != nil {
+				return nil, false
+			}
+			node = n[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setPath sets the value at segments below node, creating missing objects, and returns the
+// updated node.
+func setPath(node interface{}, segments []string, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	switch n := node.(type) {
+	case nil:
+		child, err := setPath(nil, segments[1:], value)
+		return map[string]interface{}{segments[0]: child}, err
+	case map[string]interface{}:
+		child, err := setPath(n[segments[0]], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[segments[0]] = child
+		return n, nil
+	case []interface{}:
+		index, err := listIndex(n, segments[0])
+		if err != nil {
+			return nil, err
+		}
+		child, err := setPath(n[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("'%s' is below a value that is not an object", segments[0])
+	}
+}
+
+// unsetPath removes the value at segments below node. It returns the updated node and
+// whether the value existed.
+func unsetPath(node interface{}, segments []string) (interface{}, bool) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[segments[0]]
+		if !ok {
+			return n, false
+		}
+		if len(segments) == 1 {
+			delete(n, segments[0])
+			return n, true
+		}
+		updated, found := unsetPath(child, segments[1:])
+		n[segments[0]] = updated
+		return n, found
+	case []interface{}:
+		index, err := listIndex(n, segments[0])
+		if err != nil {
+			return n, false
+		}
+		if len(segments) == 1 {
+			return append(n[:index], n[index+1:]...), true
+		}
+		updated, found := unsetPath(n[index], segments[1:])
+		n[index] = updated
+		return n, found
+	default:
+		return node, false
+	}
+}
+
+// maskSecrets returns a copy of node with the values of secret settings masked.
+func maskSecrets(node interface{}, name string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			masked[key] = maskSecrets(value, key)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(n))
+		for i, value := range n {
+			masked[i] = maskSecrets(value, name)
+		}
+		return masked
+	case string:
+		if secretKeys[name] && n != "" {
+			return MaskedValue
+		}
+	}
+	return node
+}
+
+// findSecret returns the dotted key of the first secret setting inside node, which is the
+// value of key, or "" if there is none.
+func findSecret(node interface{}, key string) string {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(n))
+		for name := range n {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if secretKeys[name] {
+				return joinKey(key, name)
+			}
+			if secret := findSecret(n[name], joinKey(key, name)); secret != "" {
+				return secret
+			}
+		}
+	case []interface{}:
+		for i, value := range n {
+			if secret := findSecret(value, joinKey(key, strconv.Itoa(i))); secret != "" {
+				return secret
+			}
+		}
+	}
+	return ""
+}
+
+// GetValue returns the value of a dotted key, with secrets masked. Objects are returned as
+// maps and lists as slices. An empty key returns the whole configuration.
+func GetValue(key string) (interface{}, error) {
+	tree, err := GetConfig().tree()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return maskSecrets(tree, ""), nil
+	}
+
+	segments, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lookupPath(tree, segments)
+	if !ok {
+		return nil, fmt.Errorf("key '%s' is not set", key)
+	}
+	return maskSecrets(value, segments[len(segments)-1]), nil
+}
+
+// Flatten lists the scalar values below value, sorted by their dotted keys. key is the
+// dotted key of value itself. Empty objects and lists are listed as values.
+func Flatten(key string, value interface{}) []Setting {
+	var settings []Setting
+	switch v := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			settings = append(settings, Flatten(joinKey(key, name), v[name])...)
+		}
+		if len(v) > 0 {
+			return settings
+		}
+	case []interface{}:
+		for i, element := range v {
+			settings = append(settings, Flatten(joinKey(key, strconv.Itoa(i)), element)...)
+		}
+		if len(v) > 0 {
+			return settings
+		}
+	}
+	return append(settings, Setting{Key: key, Value: value})
+}
+
+// FormatValue formats a configuration value for text output: strings as they are, other
+// values as JSON.
+func FormatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// SetValue sets a dotted key to value, validates the result and saves it. The value is
+// stored as a string if the setting takes one, otherwise it is parsed as JSON, so numbers,
+// booleans and objects can be set too.
+func SetValue(key, value string) error {
+	if ReadOnly() {
+		return fmt.Errorf("the configuration is read-only")
+	}
+	segments, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	if secretKeys[segments[len(segments)-1]] {
+		return fmt.Errorf("'%s' is a secret and is not set in the configuration, use 'floom login'", key)
+	}
+
+	candidates := []interface{}{value}
+	var parsed interface{}
+	if json.Unmarshal([]byte(value), &parsed) == nil {
+		// Objects must not smuggle in secrets either
+		if secret := findSecret(parsed, key); secret != "" {
+			return fmt.Errorf("'%s' is a secret and is not set in the configuration, use 'floom login'", secret)
+		}
+		candidates = append(candidates, parsed)
+	}
+
+	var typeErr error
+	for _, candidate := range candidates {
+		err := replaceConfig(func(_ *AppConfig, tree map[string]interface{}) (interface{}, error) {
+			updated, err := setPath(tree, segments, candidate)
+			if err != nil {
+				return nil, fmt.Errorf("cannot set '%s': %w", key, err)
+			}
+			return updated, nil
+		})
+		var unmarshalErr *json.UnmarshalTypeError
+		if !errors.As(err, &unmarshalErr) {
+			return keyError(key, err)
+		}
+		typeErr = fmt.Errorf("invalid value for '%s', expected %s", key, unmarshalErr.Type)
+	}
+	return typeErr
+}
+
+// UnsetValue removes a dotted key, validates the result and saves it. It reports whether
+// the key was set.
+func UnsetValue(key string) (bool, error) {
+	if ReadOnly() {
+		return false, fmt.Errorf("the configuration is read-only")
+	}
+	segments, err := parseKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	var previousRefs map[string]bool
+	err = replaceConfig(func(current *AppConfig, tree map[string]interface{}) (interface{}, error) {
+		updated, found := unsetPath(tree, segments)
+		if !found {
+			return nil, errKeyNotSet
+		}
+		previousRefs = current.credentialRefs()
+		return updated, nil
+	})
+	if errors.Is(err, errKeyNotSet) {
+		return false, nil
+	}
+	if err != nil {
+		return true, keyError(key, err)
+	}
+
+	// Like RemoveCredentials, keys that are not referenced any more are deleted from their store
+	remainingRefs := GetConfig().credentialRefs()
+	for ref := range previousRefs {
+		if remainingRefs[ref] {
+			continue
+		}
+		if err := appConfig.deleteApiKey(DeploymentCredentials{ApiKeyRef: ref}); err != nil {
+			return true, fmt.Errorf("'%s' removed, but the API key could not be deleted from the credential store: %w", key, err)
+		}
+	}
+	return true, nil
+}
+
+// keyError describes errors of decoding an updated configuration in terms of the key.
+func keyError(key string, err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field") {
+		return fmt.Errorf("unknown key '%s'", key)
+	}
+	return err
+}
+
+// EditableConfig returns the configuration as it is saved, for editing.
+func EditableConfig() ([]byte, error) {
+	data, err := json.MarshalIndent(GetConfig(), "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ReplaceConfig validates an edited configuration and saves it in place of the current one.
+func ReplaceConfig(data []byte) error {
+	if ReadOnly() {
+		return fmt.Errorf("the configuration is read-only")
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	err := replaceConfig(func(*AppConfig, map[string]interface{}) (interface{}, error) {
+		return tree, nil
+	})
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return fmt.Errorf("unknown key %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+// errKeyNotSet stops an update that has nothing to remove.
+var errKeyNotSet = errors.New("key is not set")
+
+// replaceConfig updates the configuration under the config lock: change returns the new
+// JSON form of the configuration from the current one. The result is decoded strictly, so
+// unknown keys are rejected, and validated before it is saved.
+func replaceConfig(change func(current *AppConfig, tree map[string]interface{}) (interface{}, error)) error {
+	return GetConfig().Update(func(current *AppConfig) error {
+		tree, err := current.tree()
+		if err != nil {
+			return err
+		}
+		replaced, err := change(current, tree)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(replaced)
+		if err != nil {
+			return fmt.Errorf("failed to encode config: %v", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		updated := &AppConfig{}
+		if err := decoder.Decode(updated); err != nil {
+			return err
+		}
+		if err := updated.Validate(); err != nil {
+			return err
+		}
+
+		*current = *updated
+		return nil
+	})
+}
@@ -0,0 +1,320 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useTestConfig makes the package functions use config, saved to a temporary directory
+// together with its credential file.
+func useTestConfig(t *testing.T, config *AppConfig) {
+	t.Helper()
+	t.Setenv(configEnvVar, filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv(profileEnvVar, "")
+	t.Setenv(readOnlyEnvVar, "")
+	t.Setenv(passphraseEnvVar, "correct horse")
+	PassphrasePrompt = nil
+
+	previousConfig, previousStores := appConfig, credentialStores
+	appConfig, credentialStores = config, map[string]CredentialStore{}
+	t.Cleanup(func() { appConfig, credentialStores = previousConfig, previousStores })
+
+	if err := config.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	useTestConfig(t, &AppConfig{SchemaVersion: CurrentSchemaVersion})
+
+	tests := []struct {
+		key     string
+		want    []string
+		wantErr string
+	}{
+		{key: "credential_store.backend", want: []string{"credential_store", "backend"}},
+		{key: "deployments.cloud.network.timeout", want: []string{"profiles", "default", "deployments", "cloud", "network", "timeout"}},
+		{key: "deployments[https://floom.example.com].credentials.username", want: []string{"profiles", "default", "deployments", "https://floom.example.com", "credentials", "username"}},
+		{key: "profiles.team.deployments[a.b][0]", want: []string{"profiles", "team", "deployments", "a.b", "0"}},
+		{key: "deployments.cloud.pipelines.0.name", want: []string{"profiles", "default", "deployments", "cloud", "pipelines", "0", "name"}},
+		{key: "", wantErr: "invalid key"},
+		{key: "deployments.", wantErr: "invalid key"},
+		{key: "deployments..cloud", wantErr: "empty segment"},
+		{key: "deployments[]", wantErr: "empty segment"},
+		{key: "deployments[cloud", wantErr: "missing ']'"},
+		{key: "deployments[cloud]network", wantErr: "expected '.' after ']'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			segments, err := parseKey(test.key)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseKey(%q) error = %v, want %q", test.key, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKey(%q) failed: %v", test.key, err)
+			}
+			if !reflect.DeepEqual(segments, test.want) {
+				t.Errorf("parseKey(%q) = %q, want %q", test.key, segments, test.want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     interface{}
+		segments []string
+		want     interface{}
+		wantErr  string
+	}{
+		{
+			name:     "creates objects",
+			segments: []string{"network", "tls", "ca_file"},
+			want:     map[string]interface{}{"network": map[string]interface{}{"tls": map[string]interface{}{"ca_file": "value"}}},
+		},
+		{
+			name:     "keeps siblings",
+			node:     map[string]interface{}{"network": map[string]interface{}{"timeout": "30s"}},
+			segments: []string{"network", "proxy_url"},
+			want:     map[string]interface{}{"network": map[string]interface{}{"timeout": "30s", "proxy_url": "value"}},
+		},
+		{
+			name:     "list entry",
+			node:     map[string]interface{}{"pipelines": []interface{}{map[string]interface{}{"name": "docs"}}},
+			segments: []string{"pipelines", "0", "name"},
+			want:     map[string]interface{}{"pipelines": []interface{}{map[string]interface{}{"name": "value"}}},
+		},
+		{
+			name:     "index out of range",
+			node:     map[string]interface{}{"pipelines": []interface{}{}},
+			segments: []string{"pipelines", "0", "name"},
+			wantErr:  "not an index of a list with 0 entries",
+		},
+		{
+			name:     "below a scalar",
+			node:     map[string]interface{}{"network": "none"},
+			segments: []string{"network", "timeout"},
+			wantErr:  "below a value that is not an object",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated, err := setPath(test.node, test.segments, "value")
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("setPath error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setPath failed: %v", err)
+			}
+			if !reflect.DeepEqual(updated, test.want) {
+				t.Errorf("setPath = %v, want %v", updated, test.want)
+			}
+		})
+	}
+}
+
+func TestFindSecret(t *testing.T) {
+	tests := []struct {
+		name string
+		node interface{}
+		want string
+	}{
+		{name: "no secret", node: map[string]interface{}{"credentials": map[string]interface{}{"username": "ada"}}},
+		{name: "scalar", node: "value"},
+		{
+			name: "nested",
+			node: map[string]interface{}{"cloud": map[string]interface{}{"credentials": map[string]interface{}{"api_key": "sk-secret"}}},
+			want: "profiles.team.deployments.cloud.credentials.api_key",
+		},
+		{
+			name: "in a list",
+			node: map[string]interface{}{"endpoints": []interface{}{"a", map[string]interface{}{"api_key": "sk-secret"}}},
+			want: "profiles.team.deployments.endpoints.1.api_key",
+		},
+		{
+			name: "custom endpoint",
+			node: map[string]interface{}{"https://floom.example.com": map[string]interface{}{"credentials": map[string]interface{}{"api_key": ""}}},
+			want: "profiles.team.deployments[https://floom.example.com].credentials.api_key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := findSecret(test.node, "profiles.team.deployments"); got != test.want {
+				t.Errorf("findSecret = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	useTestConfig(t, &AppConfig{SchemaVersion: CurrentSchemaVersion})
+
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    interface{}
+		wantErr string
+	}{
+		{name: "string", key: "deployments.cloud.network.timeout", value: "30s", want: "30s"},
+		{name: "string that is JSON", key: "deployments.cloud.credentials.username", value: "42", want: "42"},
+		{name: "number", key: "deployments.cloud.network.max_retries", value: "5", want: float64(5)},
+		{name: "boolean", key: "deployments.cloud.network.tls.insecure_skip_verify", value: "true", want: true},
+		{
+			name:  "object",
+			key:   "deployments.cloud.network.endpoints.upload",
+			value: `{"timeout": "10m", "max_retries": 0}`,
+			want:  map[string]interface{}{"timeout": "10m", "max_retries": float64(0)},
+		},
+		{name: "wrong type", key: "deployments.cloud.network.max_retries", value: "many", wantErr: "invalid value for 'deployments.cloud.network.max_retries', expected int"},
+		{name: "invalid setting", key: "deployments.cloud.network.timeout", value: "soon", wantErr: "timeout"},
+		{name: "unknown key", key: "deployments.cloud.network.speed", value: "fast", wantErr: "unknown key"},
+		{name: "secret", key: "deployments.cloud.credentials.api_key", value: "sk-secret", wantErr: "is a secret"},
+		{
+			name:    "nested secret",
+			key:     "deployments.staging",
+			value:   `{"credentials": {"username": "ada", "api_key": "sk-secret"}}`,
+			wantErr: "'deployments.staging.credentials.api_key' is a secret",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := SetValue(test.key, test.value)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("SetValue error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetValue failed: %v", err)
+			}
+
+			value, err := GetValue(test.key)
+			if err != nil {
+				t.Fatalf("GetValue failed: %v", err)
+			}
+			if !reflect.DeepEqual(value, test.want) {
+				t.Errorf("GetValue = %#v, want %#v", value, test.want)
+			}
+		})
+	}
+
+	if _, exists := ActiveProfile().Deployments["staging"]; exists {
+		t.Errorf("deployment with a secret was saved")
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	useTestConfig(t, &AppConfig{SchemaVersion: CurrentSchemaVersion})
+	for _, target := range []string{"cloud", "staging"} {
+		if err := UpdateUserConfig("key-of-"+target, "ada", "", target); err != nil {
+			t.Fatalf("failed to log in to %s: %v", target, err)
+		}
+	}
+	refs := GetConfig().credentialRefs()
+	if len(refs) != 2 {
+		t.Fatalf("credential refs = %v, want one per deployment", refs)
+	}
+
+	tests := []struct {
+		key       string
+		wantFound bool
+		wantKeys  map[string]string
+	}{
+		{key: "deployments.cloud.credentials.username", wantFound: true, wantKeys: map[string]string{"file:cloud": "key-of-cloud", "file:staging": "key-of-staging"}},
+		{key: "deployments.cloud", wantFound: true, wantKeys: map[string]string{"file:staging": "key-of-staging"}},
+		{key: "deployments.cloud", wantKeys: map[string]string{"file:staging": "key-of-staging"}},
+		{key: "deployments.staging.credentials", wantFound: true, wantKeys: map[string]string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			found, err := UnsetValue(test.key)
+			if err != nil {
+				t.Fatalf("UnsetValue failed: %v", err)
+			}
+			if found != test.wantFound {
+				t.Errorf("UnsetValue = %v, want %v", found, test.wantFound)
+			}
+
+			// Keys that are not referenced any more are deleted from the credential file
+			configFilePath, _ := ConfigFilePath()
+			secrets, err := newFileCredentialStore(filepath.Join(filepath.Dir(configFilePath), credentialsFileName)).read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(secrets, test.wantKeys) {
+				t.Errorf("stored keys = %v, want %v", secrets, test.wantKeys)
+			}
+		})
+	}
+}
+
+func TestGetValueMasksSecrets(t *testing.T) {
+	useTestConfig(t, &AppConfig{
+		SchemaVersion: CurrentSchemaVersion,
+		Profiles: map[string]*ProfileConfiguration{
+			DefaultProfileName: {Deployments: map[string]DeploymentConfiguration{
+				"cloud":   {Credentials: DeploymentCredentials{ApiKey: "sk-plaintext", Username: "ada"}},
+				"staging": {Credentials: DeploymentCredentials{ApiKeyRef: "file:staging", Username: "bob"}},
+			}},
+		},
+	})
+
+	credentials := func(apiKey, apiKeyRef, username string) map[string]interface{} {
+		value := map[string]interface{}{"username": username, "nickname": ""}
+		if apiKey != "" {
+			value["api_key"] = apiKey
+		}
+		if apiKeyRef != "" {
+			value["api_key_ref"] = apiKeyRef
+		}
+		return value
+	}
+
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{key: "deployments.cloud.credentials.api_key", want: MaskedValue},
+		{key: "deployments.cloud.credentials", want: credentials(MaskedValue, "", "ada")},
+		{key: "deployments.staging.credentials", want: credentials("", "file:staging", "bob")},
+		{key: "deployments.cloud.credentials.username", want: "ada"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			value, err := GetValue(test.key)
+			if err != nil {
+				t.Fatalf("GetValue failed: %v", err)
+			}
+			if !reflect.DeepEqual(value, test.want) {
+				t.Errorf("GetValue = %#v, want %#v", value, test.want)
+			}
+		})
+	}
+
+	whole, err := GetValue("")
+	if err != nil {
+		t.Fatalf("GetValue failed: %v", err)
+	}
+	for _, setting := range Flatten("", whole) {
+		if setting.Value == "sk-plaintext" {
+			t.Errorf("GetValue of the whole configuration shows %s", setting.Key)
+		}
+	}
+}
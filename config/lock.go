@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockConfig takes an exclusive advisory lock on config.json, waiting for other runs of the
+// CLI to release it. The lock is held on a separate file next to config.json, because
+// config.json itself is replaced on every save. The returned function releases it.
+func lockConfig(configFilePath string) (func(), error) {
+	return lockPath(configFilePath, "config")
+}
+
+// lockPath takes an exclusive advisory lock on a file that is replaced on save, using a
+// separate .lock file next to it. name describes the file in errors.
+func lockPath(path, name string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s lock: %v", name, err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", name, err)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package config
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+func lockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_EX)
+}
+
+func unlockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// CurrentSchemaVersion is the version of the config.json format written by this CLI.
+// Configurations of older versions are upgraded when they are loaded.
+const CurrentSchemaVersion = 2
+
+// migrations upgrade the JSON form of a configuration by one schema version each:
+// migrations[0] upgrades version 1 to 2, and so on. New format changes add a migration
+// and raise CurrentSchemaVersion.
+var migrations = []func(tree map[string]interface{}) error{
+	migrateToProfiles,
+}
+
+// migrateToProfiles upgrades version 1, with a single set of deployments and a
+// "config_version" of "1.0", to version 2, where deployments belong to profiles. The
+// deployments become the default profile.
+func migrateToProfiles(tree map[string]interface{}) error {
+	delete(tree, "config_version")
+
+	deployments, ok := tree["deployments"].(map[string]interface{})
+	delete(tree, "deployments")
+	if !ok || len(deployments) == 0 {
+		return nil
+	}
+
+	profiles, ok := tree["profiles"].(map[string]interface{})
+	if !ok {
+		profiles = map[string]interface{}{}
+		tree["profiles"] = profiles
+	}
+	profile, ok := profiles[DefaultProfileName].(map[string]interface{})
+	if !ok {
+		profile = map[string]interface{}{}
+		profiles[DefaultProfileName] = profile
+	}
+	profileDeployments, ok := profile["deployments"].(map[string]interface{})
+	if !ok {
+		profileDeployments = map[string]interface{}{}
+		profile["deployments"] = profileDeployments
+	}
+
+	for deploymentType, deployment := range deployments {
+		if _, exists := profileDeployments[deploymentType]; exists {
+			return fmt.Errorf("deployment '%s' exists both in the legacy deployments and in the default profile", deploymentType)
+		}
+		profileDeployments[deploymentType] = deployment
+	}
+	return nil
+}
+
+// schemaVersion returns the schema version of the JSON form of a configuration.
+// Configurations without one were written before versions existed and are version 1.
+func schemaVersion(tree map[string]interface{}) (int, error) {
+	switch version := tree["schema_version"].(type) {
+	case nil:
+		return 1, nil
+	case float64:
+		if version >= 1 && version == math.Trunc(version) {
+			return int(version), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid schema_version %v", tree["schema_version"])
+}
+
+// decodeConfig decodes config.json, upgrading older schema versions. Before each upgrade,
+// the file is backed up as e.g. config.json.v1.bak, unless backup is false. It reports
+// whether the configuration was upgraded and should be saved.
+func decodeConfig(path string, data []byte, backup bool) (*AppConfig, bool, error) {
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, false, fmt.Errorf("failed to decode config file: %v", err)
+	}
+
+	version, err := schemaVersion(tree)
+	if err != nil {
+		return nil, false, err
+	}
+	if version > CurrentSchemaVersion {
+		return nil, false, fmt.Errorf("%s was written by a newer version of the Floom CLI (schema version %d, this version supports up to %d). Update the CLI, or use --config to select another file", path, version, CurrentSchemaVersion)
+	}
+
+	original := version
+	for ; version < CurrentSchemaVersion; version++ {
+		if backup {
+			// The file as it was is kept byte for byte, later steps back up their input
+			state := data
+			if version != original {
+				if state, err = json.MarshalIndent(tree, "", "    "); err != nil {
+					return nil, false, fmt.Errorf("failed to back up config file: %v", err)
+				}
+				state = append(state, '\n')
+			}
+			backupPath := fmt.Sprintf("%s.v%d.bak", path, version)
+			if err := writeFileAtomic(backupPath, state, 0600); err != nil {
+				return nil, false, fmt.Errorf("failed to back up config file: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Upgrading the configuration from schema version %d to %d, a backup is kept at %s.\n", version, version+1, backupPath)
+		}
+
+		if err := migrations[version-1](tree); err != nil {
+			return nil, false, fmt.Errorf("failed to upgrade config file from schema version %d: %v", version, err)
+		}
+		tree["schema_version"] = version + 1
+	}
+
+	// Round trip through JSON to decode the upgraded configuration
+	upgradedData, err := json.Marshal(tree)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode config file: %v", err)
+	}
+	config := &AppConfig{}
+	if err := json.Unmarshal(upgradedData, config); err != nil {
+		return nil, false, fmt.Errorf("failed to decode config file: %v", err)
+	}
+	return config, original < CurrentSchemaVersion, nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		upgraded    bool
+		deployments []string
+		wantErr     string
+	}{
+		{
+			name:        "version 1",
+			data:        `{"config_version": "1.0", "deployments": {"cloud": {"credentials": {"username": "alice"}}}}`,
+			upgraded:    true,
+			deployments: []string{"cloud"},
+		},
+		{
+			name:     "version 1 without deployments",
+			data:     `{"config_version": "1.0"}`,
+			upgraded: true,
+		},
+		{
+			name:        "current version",
+			data:        `{"schema_version": 2, "profiles": {"default": {"deployments": {"local": {}}}}}`,
+			deployments: []string{"local"},
+		},
+		{
+			name:    "conflicting deployments",
+			data:    `{"deployments": {"cloud": {}}, "profiles": {"default": {"deployments": {"cloud": {}}}}}`,
+			wantErr: "exists both",
+		},
+		{
+			name:    "newer version",
+			data:    `{"schema_version": 3}`,
+			wantErr: "newer version",
+		},
+		{
+			name:    "invalid version",
+			data:    `{"schema_version": "two"}`,
+			wantErr: "invalid schema_version",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			config, upgraded, err := decodeConfig(path, []byte(test.data), true)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("decodeConfig error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeConfig failed: %v", err)
+			}
+
+			if upgraded != test.upgraded {
+				t.Errorf("upgraded = %v, want %v", upgraded, test.upgraded)
+			}
+			if config.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("schema version = %d, want %d", config.SchemaVersion, CurrentSchemaVersion)
+			}
+			deployments := config.Profile(DefaultProfileName).Deployments
+			if len(deployments) != len(test.deployments) {
+				t.Errorf("default profile has %d deployments, want %v", len(deployments), test.deployments)
+			}
+			for _, deploymentType := range test.deployments {
+				if _, ok := deployments[deploymentType]; !ok {
+					t.Errorf("default profile has no deployment '%s'", deploymentType)
+				}
+			}
+
+			// The first backup is the original file, byte for byte
+			backup, err := os.ReadFile(path + ".v1.bak")
+			switch {
+			case test.upgraded && err != nil:
+				t.Errorf("no backup written: %v", err)
+			case test.upgraded && string(backup) != test.data:
+				t.Errorf("backup is %q, want the original %q", backup, test.data)
+			case !test.upgraded && err == nil:
+				t.Errorf("backup written for a current configuration")
+			}
+		})
+	}
+}
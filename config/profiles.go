@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfileName is the profile used when none is selected. Configurations written
+// before profiles existed become this profile.
+const DefaultProfileName = "default"
+
+// profileEnvVar selects the profile for a run, unless --profile is given.
+const profileEnvVar = "FLOOM_PROFILE"
+
+// profileNamePattern matches valid profile names.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ProfileConfiguration holds the deployments, credentials and pipeline registry of one
+// account or environment.
+type ProfileConfiguration struct {
+	Deployments map[string]DeploymentConfiguration `json:"deployments"`
+}
+
+// selectedProfile is the profile given on the command line, if any.
+var selectedProfile string
+
+// SelectProfile selects the profile for this run, e.g. from a --profile flag. An empty name
+// falls back to FLOOM_PROFILE, the profile of the project file and then to the default set
+// with 'floom profile use'. When validate is set, the resulting profile must exist.
+func SelectProfile(name string, validate bool) error {
+	selectedProfile = name
+
+	active := ActiveProfileName()
+	if !validate || active == DefaultProfileName {
+		return nil
+	}
+	if _, exists := GetConfig().Profiles[active]; !exists {
+		return fmt.Errorf("profile '%s' does not exist, create it with 'floom profile create %s'", active, active)
+	}
+	return nil
+}
+
+// ActiveProfileName returns the name of the profile used by this run.
+func ActiveProfileName() string {
+	if selectedProfile != "" {
+		return selectedProfile
+	}
+	if name := os.Getenv(profileEnvVar); name != "" {
+		return name
+	}
+	if name := projectSetting(func(p *ProjectConfiguration) string { return p.Profile }); name != "" {
+		return name
+	}
+	if appConfig != nil && appConfig.DefaultProfile != "" {
+		return appConfig.DefaultProfile
+	}
+	return DefaultProfileName
+}
+
+// ActiveProfile returns the profile used by this run.
+func ActiveProfile() *ProfileConfiguration {
+	return GetConfig().Profile(ActiveProfileName())
+}
+
+// Profile returns a profile by name, creating it if it does not exist.
+func (c *AppConfig) Profile(name string) *ProfileConfiguration {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*ProfileConfiguration)
+	}
+	profile, exists := c.Profiles[name]
+	if !exists || profile == nil {
+		profile = &ProfileConfiguration{}
+		c.Profiles[name] = profile
+	}
+	if profile.Deployments == nil {
+		profile.Deployments = make(map[string]DeploymentConfiguration)
+	}
+	return profile
+}
+
+// ProfileNames returns the names of all profiles, sorted, including the default profile.
+func ProfileNames() []string {
+	names := []string{DefaultProfileName}
+	for name := range GetConfig().Profiles {
+		if name != DefaultProfileName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// profileExists reports whether a profile exists. The default profile always exists.
+func (c *AppConfig) profileExists(name string) bool {
+	_, exists := c.Profiles[name]
+	return exists || name == DefaultProfileName
+}
+
+// CreateProfile creates an empty profile.
+func CreateProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s', use letters, digits, '.', '-' and '_'", name)
+	}
+
+	return appConfig.Update(func(current *AppConfig) error {
+		if current.profileExists(name) {
+			return fmt.Errorf("profile '%s' already exists", name)
+		}
+		current.Profile(name)
+		return nil
+	})
+}
+
+// CopyProfile creates a profile with the deployments, pipelines and credentials of another
+// one. API keys are copied in the credential store, so either profile can be deleted later.
+func CopyProfile(source, target string) error {
+	if !profileNamePattern.MatchString(target) {
+		return fmt.Errorf("invalid profile name '%s', use letters, digits, '.', '-' and '_'", target)
+	}
+
+	// Stores may ask for a passphrase, which must not happen while the config is locked
+	apiKeys := map[string]string{}
+	if appConfig.profileExists(source) {
+		for deploymentType, deployment := range appConfig.Profile(source).Deployments {
+			if deployment.Credentials.ApiKeyRef == "" {
+				continue
+			}
+			apiKey, err := appConfig.resolveApiKey(deployment.Credentials)
+			if err != nil {
+				return fmt.Errorf("deployment '%s': %w", deploymentType, err)
+			}
+			apiKeys[deployment.Credentials.ApiKeyRef] = apiKey
+		}
+		if len(apiKeys) > 0 {
+			if err := appConfig.prepareCredentialStore(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return appConfig.Update(func(current *AppConfig) error {
+		if !current.profileExists(source) {
+			return fmt.Errorf("profile '%s' does not exist", source)
+		}
+		if current.profileExists(target) {
+			return fmt.Errorf("profile '%s' already exists", target)
+		}
+
+		deployments := current.Profile(source).Deployments
+		copied := &ProfileConfiguration{Deployments: make(map[string]DeploymentConfiguration, len(deployments))}
+		for deploymentType, deployment := range deployments {
+			// Pipelines and network settings are copied, not shared
+			deployment.Pipelines = append([]PipelineConfiguration{}, deployment.Pipelines...)
+			if deployment.Network != nil {
+				network := *deployment.Network
+				deployment.Network = &network
+			}
+
+			if deployment.Credentials.ApiKeyRef != "" {
+				apiKey, resolved := apiKeys[deployment.Credentials.ApiKeyRef]
+				if !resolved {
+					return fmt.Errorf("deployment '%s' of profile '%s' changed while it was copied, try again", deploymentType, source)
+				}
+				var err error
+				if deployment.Credentials, err = current.storeApiKey(target, deploymentType, apiKey, deployment.Credentials); err != nil {
+					return fmt.Errorf("deployment '%s': %w", deploymentType, err)
+				}
+			}
+			copied.Deployments[deploymentType] = deployment
+		}
+
+		current.Profiles[target] = copied
+		return nil
+	})
+}
+
+// DeleteProfile deletes a profile and the API keys it stores. The default profile cannot be
+// deleted.
+func DeleteProfile(name string) error {
+	if name == DefaultProfileName {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+
+	var profile *ProfileConfiguration
+	err := appConfig.Update(func(current *AppConfig) error {
+		var exists bool
+		if profile, exists = current.Profiles[name]; !exists {
+			return fmt.Errorf("profile '%s' does not exist", name)
+		}
+
+		delete(current.Profiles, name)
+		if current.DefaultProfile == name {
+			current.DefaultProfile = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The profile is gone, keys left in the store are only a leftover
+	if profile != nil {
+		for deploymentType, deployment := range profile.Deployments {
+			if err := appConfig.deleteApiKey(deployment.Credentials); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not delete the API key of '%s' from the credential store: %v\n", deploymentType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// UseProfile makes a profile the default for runs without --profile or FLOOM_PROFILE.
+func UseProfile(name string) error {
+	return appConfig.Update(func(current *AppConfig) error {
+		if !current.profileExists(name) {
+			return fmt.Errorf("profile '%s' does not exist", name)
+		}
+
+		current.DefaultProfile = name
+		if name == DefaultProfileName {
+			current.DefaultProfile = ""
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Validate checks the settings of a configuration that decoding it does not check.
+func (c *AppConfig) Validate() error {
+	if c.SchemaVersion != CurrentSchemaVersion {
+		return fmt.Errorf("schema_version must be %d", CurrentSchemaVersion)
+	}
+
+	switch c.CredentialBackend() {
+	case BackendFile, BackendPlaintext:
+	case BackendHelper:
+		if c.CredentialStore.Helper == "" {
+			return fmt.Errorf("credential_store.helper must be set for the helper backend")
+		}
+		if _, err := splitCommandLine(c.CredentialStore.Helper); err != nil {
+			return fmt.Errorf("credential_store.helper: %v", err)
+		}
+	default:
+		return fmt.Errorf("credential_store.backend must be '%s', '%s' or '%s'", BackendFile, BackendHelper, BackendPlaintext)
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !profileNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid profile name '%s', use letters, digits, '.', '-' and '_'", name)
+		}
+		profile := c.Profiles[name]
+		if profile == nil {
+			continue
+		}
+		for deploymentType, deployment := range profile.Deployments {
+			if deployment.Network == nil {
+				continue
+			}
+			if err := deployment.Network.validate(); err != nil {
+				return fmt.Errorf("deployment '%s' of profile '%s': %w", deploymentType, name, err)
+			}
+		}
+	}
+
+	if c.DefaultProfile != "" && !c.profileExists(c.DefaultProfile) {
+		return fmt.Errorf("default_profile: profile '%s' does not exist", c.DefaultProfile)
+	}
+	return nil
+}
+
+func (n *NetworkConfiguration) validate() error {
+	if err := n.RequestConfiguration.validate(); err != nil {
+		return err
+	}
+	for operation, endpoint := range n.Endpoints {
+		if err := endpoint.validate(); err != nil {
+			return fmt.Errorf("endpoint '%s': %w", operation, err)
+		}
+	}
+
+	if n.TLS != nil && (n.TLS.CertFile == "") != (n.TLS.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	if n.ProxyURL != "" {
+		if proxy, err := url.Parse(n.ProxyURL); err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return fmt.Errorf("invalid proxy_url '%s'", n.ProxyURL)
+		}
+	}
+	return nil
+}
+
+func (r RequestConfiguration) validate() error {
+	durations := []struct {
+		name  string
+		value string
+	}{
+		{"timeout", r.Timeout},
+		{"initial_backoff", r.InitialBackoff},
+		{"max_backoff", r.MaxBackoff},
+	}
+	for _, duration := range durations {
+		if duration.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(duration.value); err != nil {
+			return fmt.Errorf("invalid %s '%s', use e.g. \"30s\" or \"5m\"", duration.name, duration.value)
+		}
+	}
+
+	if r.MaxRetries != nil && *r.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	return nil
+}
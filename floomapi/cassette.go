@@ -0,0 +1,382 @@
+package floomapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// cassetteBoundary replaces the random multipart boundary, so uploads of the same file
+// match across runs.
+const cassetteBoundary = "floom-cassette-boundary"
+
+// A Cassette records the API interactions of a CLI run into a JSON file, or replays them
+// from that file without touching the network. Secrets are scrubbed before they are
+// stored, so cassettes can be attached to bug reports and checked into tests.
+type Cassette struct {
+	mu     sync.Mutex
+	path   string
+	replay bool
+	file   cassetteFile
+	used   []bool
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response or transport error.
+type Interaction struct {
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	// ErrorKind classifies Error, so a replayed error is retried and reported like the
+	// original one.
+	ErrorKind string `json:"errorKind,omitempty"`
+}
+
+// Kinds of recorded transport errors.
+const (
+	errorKindDeadline = "deadline"
+	errorKindCanceled = "canceled"
+	errorKindDial     = "dial"
+	errorKindTimeout  = "timeout"
+	errorKindNetwork  = "network"
+)
+
+// errorKind classifies a transport error for the cassette.
+func errorKind(err error) string {
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorKindDeadline
+	case errors.Is(err, context.Canceled):
+		return errorKindCanceled
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return errorKindDial
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorKindTimeout
+	case errors.As(err, &netErr):
+		return errorKindNetwork
+	default:
+		return ""
+	}
+}
+
+// replayedError is a recorded transport error. It keeps the recorded message and wraps an
+// error of the recorded kind, so errors.Is and errors.As classify it like the original.
+type replayedError struct {
+	message string
+	cause   error
+}
+
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.cause
+}
+
+// replayedNetError is a recorded network error, which implements net.Error.
+type replayedNetError struct {
+	replayedError
+	timeout bool
+}
+
+func (e *replayedNetError) Timeout() bool {
+	return e.timeout
+}
+
+func (e *replayedNetError) Temporary() bool {
+	return false
+}
+
+// replayError rebuilds the error of a recorded interaction from its message and kind.
+func replayError(interaction Interaction) error {
+	message := interaction.Error
+	switch interaction.ErrorKind {
+	case errorKindDeadline:
+		return &replayedNetError{replayedError{message, context.DeadlineExceeded}, true}
+	case errorKindCanceled:
+		return &replayedError{message, context.Canceled}
+	case errorKindDial:
+		return &replayedNetError{replayedError{message, &net.OpError{Op: "dial", Err: errors.New(message)}}, false}
+	case errorKindTimeout:
+		return &replayedNetError{replayedError{message, nil}, true}
+	case errorKindNetwork:
+		return &replayedNetError{replayedError{message, nil}, false}
+	default:
+		return errors.New(message)
+	}
+}
+
+// RecordedRequest identifies a request by method, path and normalized body.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// BodySHA256 is the hash of the normalized body, used for matching.
+	BodySHA256 string `json:"bodySha256,omitempty"`
+	// Body is the normalized body for text content, kept for readers of the cassette.
+	Body string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response with its headers and body, secrets scrubbed.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"`
+}
+
+// NewRecorder creates a cassette recording every interaction into path. The file is
+// rewritten after each interaction, so it is complete even if the CLI exits early.
+func NewRecorder(path string) *Cassette {
+	return &Cassette{path: path, file: cassetteFile{Interactions: []Interaction{}}}
+}
+
+// LoadCassette reads a cassette from path for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+
+	cassette := &Cassette{path: path, replay: true}
+	if err := json.Unmarshal(data, &cassette.file); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+	}
+	cassette.used = make([]bool, len(cassette.file.Interactions))
+	return cassette, nil
+}
+
+// Wrap returns a round tripper that records the requests sent through next, or, for a
+// loaded cassette, answers them from the recording without calling next.
+func (c *Cassette) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{next: next, cassette: c}
+}
+
+type cassetteTransport struct {
+	next     http.RoundTripper
+	cassette *Cassette
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	digest := newRequestDigest(req)
+
+	if t.cassette.replay {
+		if req.Body != nil {
+			_, err := io.Copy(digest, req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t.cassette.play(req, digest.recordedRequest())
+	}
+
+	// The body is hashed while it is sent, so uploads are not held in memory
+	var body *digestingBody
+	if req.Body != nil {
+		body = &digestingBody{Reader: io.TeeReader(req.Body, digest), body: req.Body, closed: make(chan struct{})}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	resp, err := t.next.RoundTrip(req)
+	if body != nil {
+		// The transport may close the body after it returns
+		select {
+		case <-body.closed:
+		case <-req.Context().Done():
+		}
+	}
+
+	interaction := Interaction{Request: digest.recordedRequest()}
+	if err != nil {
+		interaction.Error = err.Error()
+		interaction.ErrorKind = errorKind(err)
+	} else {
+		responseBody, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+		interaction.Response = &RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: RedactHeaders(resp.Header),
+			Body:    string(RedactSecrets(responseBody)),
+		}
+	}
+
+	if recordErr := t.cassette.record(interaction); recordErr != nil {
+		return nil, recordErr
+	}
+	return resp, err
+}
+
+// digestingBody passes a request body to the transport while it is hashed. Parts the
+// transport does not send, e.g. after a failed dial, are hashed when it closes the body, so
+// the recording matches the full request on replay.
+type digestingBody struct {
+	io.Reader
+	body      io.Closer
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *digestingBody) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		io.Copy(io.Discard, b.Reader)
+		err = b.body.Close()
+		close(b.closed)
+	})
+	return err
+}
+
+// requestDigest normalizes a request for matching while its body streams through it: the
+// host is ignored and the multipart boundary is replaced by a fixed one. Text bodies up to
+// maxCapturedBody are kept, with secrets scrubbed, for readers of the cassette; larger or
+// binary bodies are only hashed.
+type requestDigest struct {
+	recorded    RecordedRequest
+	contentType string
+	boundary    []byte
+	pending     []byte
+	hash        hash.Hash
+	captured    bytes.Buffer
+	size        int64
+}
+
+func newRequestDigest(req *http.Request) *requestDigest {
+	path := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	digest := &requestDigest{
+		recorded:    RecordedRequest{Method: req.Method, Path: path},
+		contentType: req.Header.Get("Content-Type"),
+		hash:        sha256.New(),
+	}
+	if mediaType, params, err := mime.ParseMediaType(digest.contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		digest.boundary = []byte(params["boundary"])
+	}
+	return digest
+}
+
+// Write adds a chunk of the body. The end of a chunk that may begin a boundary is held back
+// until the next one.
+func (d *requestDigest) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	if d.boundary == nil {
+		d.add(p)
+		return len(p), nil
+	}
+
+	d.pending = append(d.pending, p...)
+	for {
+		i := bytes.Index(d.pending, d.boundary)
+		if i < 0 {
+			break
+		}
+		d.add(d.pending[:i])
+		d.add([]byte(cassetteBoundary))
+		d.pending = d.pending[i+len(d.boundary):]
+	}
+	if keep := len(d.boundary) - 1; len(d.pending) > keep {
+		d.add(d.pending[:len(d.pending)-keep])
+		d.pending = append([]byte(nil), d.pending[len(d.pending)-keep:]...)
+	}
+	return len(p), nil
+}
+
+func (d *requestDigest) add(p []byte) {
+	d.hash.Write(p)
+	if d.captured.Len() <= maxCapturedBody {
+		d.captured.Write(p[:min(len(p), maxCapturedBody+1-d.captured.Len())])
+	}
+}
+
+// recordedRequest completes the digest once the whole body was written.
+func (d *requestDigest) recordedRequest() RecordedRequest {
+	recorded := d.recorded
+	if d.size == 0 {
+		return recorded
+	}
+	d.add(d.pending)
+	d.pending = nil
+
+	sum := d.hash.Sum(nil)
+	if isTextContent(d.contentType) && d.captured.Len() <= maxCapturedBody {
+		body := RedactSecrets(d.captured.Bytes())
+		hash := sha256.Sum256(body)
+		sum = hash[:]
+		recorded.Body = string(body)
+	}
+	recorded.BodySHA256 = hex.EncodeToString(sum)
+	return recorded
+}
+
+func (c *Cassette) record(interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.file.Interactions = append(c.file.Interactions, interaction)
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
+
+// play answers a request with the first unused interaction that matches it. Interactions
+// are used once, so a recorded retry sequence is replayed in order.
+func (c *Cassette) play(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.file.Interactions {
+		candidate := interaction.Request
+		if c.used[i] || candidate.Method != recorded.Method || candidate.Path != recorded.Path || candidate.BodySHA256 != recorded.BodySHA256 {
+			continue
+		}
+		c.used[i] = true
+
+		if interaction.Response == nil {
+			return nil, replayError(interaction)
+		}
+		headers := interaction.Response.Headers
+		if headers == nil {
+			headers = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction in %s matches %s %s", c.path, recorded.Method, recorded.Path)
+}
@@ -0,0 +1,263 @@
+package floomapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCassetteReplaysErrorKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		deadline  bool
+		canceled  bool
+		dial      bool
+		netError  bool
+		timeout   bool
+		retryable bool
+	}{
+		{name: "deadline", err: context.DeadlineExceeded, deadline: true, netError: true, timeout: true, retryable: true},
+		{name: "canceled", err: context.Canceled, canceled: true},
+		{name: "dial", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, dial: true, netError: true, retryable: true},
+		{name: "timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, netError: true, timeout: true, retryable: true},
+		{name: "other", err: errors.New("malformed response")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cassette.json")
+			send(t, NewRecorder(path).Wrap(failingTransport{test.err}))
+
+			cassette, err := LoadCassette(path)
+			if err != nil {
+				t.Fatalf("failed to load cassette: %v", err)
+			}
+			replayed := send(t, cassette.Wrap(failingTransport{errors.New("network used during replay")}))
+
+			if replayed.Error() != test.err.Error() {
+				t.Errorf("replayed message %q, want %q", replayed.Error(), test.err.Error())
+			}
+			var opErr *net.OpError
+			var netErr net.Error
+			isNetError := errors.As(replayed, &netErr)
+			checks := []struct {
+				what      string
+				got, want bool
+			}{
+				{"deadline exceeded", errors.Is(replayed, context.DeadlineExceeded), test.deadline},
+				{"canceled", errors.Is(replayed, context.Canceled), test.canceled},
+				{"dial error", errors.As(replayed, &opErr) && opErr.Op == "dial", test.dial},
+				{"net.Error", isNetError, test.netError},
+				{"timeout", isNetError && netErr.Timeout(), test.timeout},
+				{"retryable", isRetryableError(replayed, true), test.retryable},
+			}
+			for _, check := range checks {
+				if check.got != check.want {
+					t.Errorf("replayed error is %s: %v, want %v", check.what, check.got, check.want)
+				}
+			}
+		})
+	}
+}
+
+func send(t *testing.T, transport http.RoundTripper) error {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://localhost/v1/Pipelines", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transport.RoundTrip(req)
+	if err == nil {
+		t.Fatal("round trip succeeded, want an error")
+	}
+	return err
+}
+
+// cassetteClient creates a client sending its requests through a cassette. Retries are
+// quick, so recorded retry sequences stay short.
+func cassetteClient(baseURL string, transport http.RoundTripper) *Client {
+	settings := RequestSettings{Timeout: 5 * time.Second, MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	return NewClient(baseURL, Credentials{ApiKey: "sk-header-secret"}, WithRequestSettings(settings), WithTransport(transport))
+}
+
+// replayOnly fails every request that reaches the network during a replay.
+var replayOnly = failingTransport{errors.New("network used during replay")}
+
+func TestCassetteReplaysUploads(t *testing.T) {
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := r.FormFile("file"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"fileId": "asset-%d"}`, uploads.Add(1))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	// The second file is larger than the part of a body kept in the cassette
+	files := map[string][]byte{
+		"small.pdf": bytes.Repeat([]byte("small "), 100),
+		"large.pdf": bytes.Repeat([]byte("large "), 1<<20),
+		"other.pdf": bytes.Repeat([]byte("other "), 100),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "cassette.json")
+	recorder := cassetteClient(server.URL, NewRecorder(path).Wrap(http.DefaultTransport))
+	recorded := map[string]string{}
+	for _, name := range []string{"small.pdf", "large.pdf"} {
+		fileId, err := recorder.UploadAsset(context.Background(), filepath.Join(dir, name), nil)
+		if err != nil {
+			t.Fatalf("failed to record upload of %s: %v", name, err)
+		}
+		recorded[name] = fileId
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	// Every upload has a new multipart boundary, and the order of the recording is not needed
+	player := cassetteClient("http://replay.invalid", cassette.Wrap(replayOnly))
+	for _, name := range []string{"large.pdf", "small.pdf"} {
+		fileId, err := player.UploadAsset(context.Background(), filepath.Join(dir, name), nil)
+		if err != nil {
+			t.Fatalf("failed to replay upload of %s: %v", name, err)
+		}
+		if fileId != recorded[name] {
+			t.Errorf("replayed upload of %s = %q, want %q", name, fileId, recorded[name])
+		}
+	}
+
+	if _, err := player.UploadAsset(context.Background(), filepath.Join(dir, "other.pdf"), nil); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("replayed upload of a file that was not recorded: %v, want no recorded interaction", err)
+	}
+}
+
+func TestCassetteScrubsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Write([]byte(`{"apiKey": "sk-response-secret", "userId": "user-1"}`))
+	}))
+	defer server.Close()
+
+	commit := func(transport http.RoundTripper) string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/Pipelines/Commit", strings.NewReader("pipeline:\n  name: docs\n  apiKey: sk-body-secret\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "text/yaml")
+		req.Header.Set("Api-Key", "sk-header-secret")
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("round trip failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	commit(NewRecorder(path).Wrap(http.DefaultTransport))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sk-header-secret", "sk-body-secret", "sk-response-secret", "cookie-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if want := "pipeline:\n  name: docs\n  apiKey: REDACTED\n"; len(file.Interactions) != 1 || file.Interactions[0].Request.Body != want {
+		t.Errorf("recorded interactions %+v, want one with the body %q", file.Interactions, want)
+	}
+
+	// The scrubbed recording still matches the original request
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	if body, want := commit(cassette.Wrap(replayOnly)), `{"apiKey": "REDACTED", "userId": "user-1"}`; body != want {
+		t.Errorf("replayed response %q, want %q", body, want)
+	}
+}
+
+func TestCassetteReplaysRetrySequence(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			http.Error(w, "starting up", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status": "Healthy"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if _, err := cassetteClient(server.URL, NewRecorder(path).Wrap(http.DefaultTransport)).Health(context.Background()); err != nil {
+		t.Fatalf("failed to record health check: %v", err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	if len(cassette.file.Interactions) != 3 {
+		t.Fatalf("recorded %d interactions, want 3", len(cassette.file.Interactions))
+	}
+
+	// Each recorded attempt answers one request, so the replay retries through the 503s
+	player := cassetteClient("http://replay.invalid", cassette.Wrap(replayOnly))
+	if _, err := player.Health(context.Background()); err != nil {
+		t.Fatalf("failed to replay health check: %v", err)
+	}
+	for i, used := range cassette.used {
+		if !used {
+			t.Errorf("interaction %d was not replayed", i)
+		}
+	}
+	if _, err := player.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("second replayed health check: %v, want no recorded interaction", err)
+	}
+}
@@ -0,0 +1,426 @@
+package floomapi
+
+import (
+	"FloomCLI/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single attempt of an API request without progress. Uploads may
+// take longer, as long as their body keeps streaming.
+const DefaultTimeout = 5 * time.Minute
+
+// API is the set of Floom API operations used by the CLI. It is implemented by Client and
+// can be replaced by a fake to test commands without a Floom server.
+type API interface {
+	// Register registers a new anonymous user and returns its credentials.
+	Register(ctx context.Context) (*Registration, error)
+	// UploadAsset uploads a file and returns the ID of the created asset. progress may be nil.
+	UploadAsset(ctx context.Context, filePath string, progress UploadProgress) (string, error)
+	// CommitPipeline commits a pipeline configuration.
+	CommitPipeline(ctx context.Context, pipeline models.PipelineDto) error
+	// VerifyApiKey checks that the server accepts the client's API key.
+	VerifyApiKey(ctx context.Context) error
+	// Health returns the health and version of the server.
+	Health(ctx context.Context) (*ServerStatus, error)
+}
+
+// Credentials authenticate requests to the Floom API.
+type Credentials struct {
+	ApiKey string
+}
+
+// Registration is the response of the user registration endpoint.
+type Registration struct {
+	ApiKey   string `json:"apiKey"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+}
+
+type assetUploadResponse struct {
+	FileId string `json:"fileId"`
+}
+
+// Client is a Floom API client bound to a single server.
+type Client struct {
+	baseURL          string
+	credentials      Credentials
+	httpClient       *http.Client
+	defaultSettings  RequestSettings
+	endpointSettings map[string]RequestSettings
+	logf             func(format string, args ...interface{})
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send its requests through httpClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout overrides the per-attempt timeout of all endpoints.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.defaultSettings.Timeout = timeout
+	}
+}
+
+// NewClient creates a client for the Floom API at baseURL.
+func NewClient(baseURL string, credentials Credentials, options ...Option) *Client {
+	client := &Client{
+		baseURL:          baseURL,
+		credentials:      credentials,
+		httpClient:       &http.Client{},
+		defaultSettings:  DefaultRequestSettings,
+		endpointSettings: map[string]RequestSettings{},
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// BaseURL returns the API base URL for a deployment target: 'local', 'cloud' or a custom
+// endpoint URL.
+func BaseURL(deploymentType string) string {
+	if deploymentType == "local" || deploymentType == "localhost" {
+		return "http://localhost:4050"
+	}
+
+	if deploymentType == "cloud" {
+		return "https://api.floom.ai"
+	}
+
+	return deploymentType
+}
+
+// Register sends a request to register a new user and returns the API key, username, and nickname.
+func (c *Client) Register(ctx context.Context) (*Registration, error) {
+	// The registration endpoint takes an empty JSON object.
+	requestBody, err := json.Marshal(map[string]string{})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request body: %v", err)
+	}
+
+	resp, err := c.do(ctx, OperationRegister, false, func(ctx context.Context) (*http.Request, error) {
+		req, err := c.newRequest(ctx, "POST", "/v1/Users/Register", bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError("registration", resp)
+	}
+
+	var registration Registration
+	if err := json.NewDecoder(resp.Body).Decode(&registration); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return &registration, nil
+}
+
+// VerifyApiKey checks the client's API key against the server. The Floom API has no endpoint
+// describing the caller, so an asset upload without a file is sent: the server rejects an
+// unknown key before it validates the form, and creates nothing for a known one.
+func (c *Client) VerifyApiKey(ctx context.Context) error {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error creating form: %w", err)
+	}
+
+	resp, err := c.do(ctx, OperationVerify, true, func(ctx context.Context) (*http.Request, error) {
+		req, err := c.newRequest(ctx, "POST", "/v1/Assets", bytes.NewReader(form.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// An accepted key gets past authentication and fails the validation of the empty form
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusOK {
+		return newError("API key verification", resp)
+	}
+	return nil
+}
+
+// Health queries the health endpoint of the server, which needs no API key.
+func (c *Client) Health(ctx context.Context) (*ServerStatus, error) {
+	resp, err := c.do(ctx, OperationHealth, true, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, "GET", "/v1/Misc/Health", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError("health check", resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	return parseServerStatus(body), nil
+}
+
+// UploadAsset streams a file as a multipart form and returns the asset ID. progress may be nil.
+func (c *Client) UploadAsset(ctx context.Context, filePath string, progress UploadProgress) (string, error) {
+	upload, err := newFileUpload(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Uploads are retried on ambiguous failures too: at worst the server keeps an unused asset
+	resp, err := c.do(ctx, OperationUpload, true, func(ctx context.Context) (*http.Request, error) {
+		requestBody, err := upload.body(progress)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := c.newRequest(ctx, "POST", "/v1/Assets", requestBody)
+		if err != nil {
+			requestBody.Close()
+			return nil, err
+		}
+		req.ContentLength = upload.contentLength()
+		// Set the content type header, including the boundary
+		req.Header.Set("Content-Type", upload.formDataContentType())
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check if the request was successful
+	if resp.StatusCode != http.StatusOK {
+		return "", newError("upload of "+filepath.Base(filePath), resp)
+	}
+
+	// Decode the JSON response
+	var uploadResponse assetUploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	return uploadResponse.FileId, nil
+}
+
+// CommitPipeline sends the pipeline to the Floom API for deployment.
+func (c *Client) CommitPipeline(ctx context.Context, pipeline models.PipelineDto) error {
+	// Marshal the PipelineDto into YAML
+	data, err := yaml.Marshal(pipeline)
+	if err != nil {
+		return fmt.Errorf("error marshaling pipeline to YAML: %w", err)
+	}
+
+	// A commit is never repeated after an ambiguous failure
+	resp, err := c.do(ctx, OperationCommit, false, func(ctx context.Context) (*http.Request, error) {
+		req, err := c.newRequest(ctx, "POST", "/v1/Pipelines/Commit", bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "text/yaml")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check if the request was successful
+	if resp.StatusCode != http.StatusOK {
+		return newError("commit of pipeline '"+pipeline.Pipeline.Name+"'", resp)
+	}
+
+	return nil
+}
+
+// UploadProgress is called while an asset is uploaded with the number of bytes of the file
+// sent so far and the file size. When an upload is retried, sent starts again at zero.
+type UploadProgress func(sent, total int64)
+
+// fileUpload streams a file as a single-part multipart form without buffering it.
+type fileUpload struct {
+	path        string
+	size        int64
+	contentType string
+	boundary    string
+	prefix      []byte
+	suffix      []byte
+}
+
+// newFileUpload prepares the multipart framing of a file upload. The framing is rendered
+// once up front so the request can carry an exact Content-Length.
+func newFileUpload(filePath string) (*fileUpload, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	contentType, err := detectContentType(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	upload := &fileUpload{path: filePath, size: info.Size(), contentType: contentType}
+
+	var framing bytes.Buffer
+	multiPartWriter := multipart.NewWriter(&framing)
+	upload.boundary = multiPartWriter.Boundary()
+	if _, err := multiPartWriter.CreatePart(upload.partHeader()); err != nil {
+		return nil, fmt.Errorf("error creating form file: %w", err)
+	}
+	upload.prefix = append([]byte(nil), framing.Bytes()...)
+	framing.Reset()
+	if err := multiPartWriter.Close(); err != nil {
+		return nil, fmt.Errorf("error closing multipart writer: %w", err)
+	}
+	upload.suffix = append([]byte(nil), framing.Bytes()...)
+
+	return upload, nil
+}
+
+func (u *fileUpload) partHeader() textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(filepath.Base(u.path))))
+	header.Set("Content-Type", u.contentType)
+	return header
+}
+
+func (u *fileUpload) contentLength() int64 {
+	return int64(len(u.prefix)) + u.size + int64(len(u.suffix))
+}
+
+func (u *fileUpload) formDataContentType() string {
+	return "multipart/form-data; boundary=" + u.boundary
+}
+
+// body streams the multipart form through an io.Pipe, reporting progress as the file is
+// read. A new body is created for every attempt.
+func (u *fileUpload) body(progress UploadProgress) (io.ReadCloser, error) {
+	file, err := os.Open(u.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer file.Close()
+
+		multiPartWriter := multipart.NewWriter(pipeWriter)
+		if err := multiPartWriter.SetBoundary(u.boundary); err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+
+		fileWriter, err := multiPartWriter.CreatePart(u.partHeader())
+		if err != nil {
+			pipeWriter.CloseWithError(fmt.Errorf("error creating form file: %w", err))
+			return
+		}
+
+		var source io.Reader = file
+		if progress != nil {
+			progress(0, u.size)
+			source = &progressReader{reader: file, total: u.size, progress: progress}
+		}
+		if _, err := io.Copy(fileWriter, source); err != nil {
+			pipeWriter.CloseWithError(fmt.Errorf("error copying file data: %w", err))
+			return
+		}
+
+		pipeWriter.CloseWithError(multiPartWriter.Close())
+	}()
+
+	return pipeReader, nil
+}
+
+// detectContentType determines the MIME type of a file from its extension, falling back to
+// sniffing its first bytes.
+func detectContentType(file *os.File) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(file.Name())); contentType != "" {
+		return contentType, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// progressReader reports the bytes read from a file.
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	total    int64
+	progress UploadProgress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.sent += int64(n)
+	r.progress(r.sent, r.total)
+	return n, err
+}
+
+// newRequest creates a request for an API path, authenticated with the client's API key.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	if c.credentials.ApiKey != "" {
+		req.Header.Set("Api-Key", c.credentials.ApiKey)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,193 @@
+package floomapi
+
+import (
+	"FloomCLI/mockserver"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeListener connects clients to a test server through synchronous in-memory pipes, so
+// a server that reads slowly holds back the client instead of a kernel buffering the body.
+type pipeListener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+func (l *pipeListener) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+// newSlowServer starts a server whose handler reads the request body in chunks of chunkSize
+// with a pause before each, then answers with an asset ID. It returns the client to reach it.
+func newSlowServer(t *testing.T, chunkSize int, pause time.Duration, settings RequestSettings) *Client {
+	t.Helper()
+	stop := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, chunkSize)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(pause):
+			}
+			if _, err := io.ReadFull(r.Body, chunk); err != nil {
+				break
+			}
+		}
+		w.Write([]byte(`{"fileId": "asset-1"}`))
+	}))
+	listener := newPipeListener()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(stop) })
+
+	return NewClient(server.URL, Credentials{},
+		WithRequestSettings(settings),
+		WithTransport(&http.Transport{DialContext: listener.DialContext}))
+}
+
+func TestUploadTimeout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "manual.pdf")
+	if err := os.WriteFile(file, make([]byte, 512<<10), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pause   time.Duration
+		wantErr bool
+	}{
+		// 32 chunks with 20ms pauses take more than twice the timeout, but data keeps flowing
+		{name: "slow upload", pause: 20 * time.Millisecond},
+		{name: "stalled upload", pause: time.Hour, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newSlowServer(t, 16<<10, test.pause, RequestSettings{Timeout: 250 * time.Millisecond})
+
+			started := time.Now()
+			fileId, err := client.UploadAsset(context.Background(), file, nil)
+			if test.wantErr {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("UploadAsset error = %v, want a deadline error", err)
+				}
+				if elapsed := time.Since(started); elapsed > 5*time.Second {
+					t.Errorf("stalled upload failed after %s, want about the timeout", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadAsset failed after %s: %v", time.Since(started), err)
+			}
+			if fileId != "asset-1" {
+				t.Errorf("UploadAsset = %q, want %q", fileId, "asset-1")
+			}
+			if elapsed := time.Since(started); elapsed < 500*time.Millisecond {
+				t.Errorf("upload took %s, the test needs it to outlast the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Hour):
+		}
+	}))
+	defer server.Close()
+
+	// Requests without a body are bounded by the timeout as a whole
+	client := NewClient(server.URL, Credentials{}, WithRequestSettings(RequestSettings{Timeout: 100 * time.Millisecond}))
+	if _, err := client.Health(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Health error = %v, want a deadline error", err)
+	}
+}
+
+func TestVerifyApiKey(t *testing.T) {
+	server := mockserver.New()
+	server.RequireAuth = true
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	registration, err := NewClient(httpServer.URL, Credentials{}).Register(context.Background())
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		wantCategory ErrorCategory
+	}{
+		{name: "registered key", apiKey: registration.ApiKey},
+		{name: "unknown key", apiKey: "unknown", wantCategory: CategoryAuth},
+		{name: "no key", wantCategory: CategoryAuth},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewClient(httpServer.URL, Credentials{ApiKey: test.apiKey}).VerifyApiKey(context.Background())
+			var apiErr *Error
+			switch {
+			case test.wantCategory == "" && err != nil:
+				t.Fatalf("VerifyApiKey failed: %v", err)
+			case test.wantCategory != "" && (!errors.As(err, &apiErr) || apiErr.Category != test.wantCategory):
+				t.Fatalf("VerifyApiKey error = %v, want a %s error", err, test.wantCategory)
+			}
+		})
+	}
+
+	// Verifying a key must not leave anything on the server
+	if assets := server.Assets(); len(assets) != 0 {
+		t.Errorf("server has assets %+v after verifying keys", assets)
+	}
+}
@@ -0,0 +1,50 @@
+package floomapi
+
+import (
+	"FloomCLI/internal/semver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ServerStatus is the response of the health endpoint. Version is empty for servers that
+// do not report it.
+type ServerStatus struct {
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+}
+
+// parseServerStatus reads the body of a successful health check. Servers that answer with
+// plain text, such as "Healthy", report only their status and no version.
+func parseServerStatus(body []byte) *ServerStatus {
+	status := &ServerStatus{}
+	if err := json.Unmarshal(body, status); err != nil {
+		status = &ServerStatus{Status: strings.Trim(strings.TrimSpace(string(body)), `"`)}
+	}
+	if status.Status == "" || strings.ContainsAny(status.Status, "\n<") {
+		status.Status = http.StatusText(http.StatusOK)
+	}
+	return status
+}
+
+// incompatibilities are server versions known not to work with this CLI. No released
+// server is known to be incompatible yet; add an entry when one is.
+var incompatibilities = []struct {
+	below  string
+	reason string
+}{}
+
+// CheckCompatibility returns why a server version is known to be incompatible with this
+// CLI, or "" if it is compatible or its version is unknown.
+func CheckCompatibility(serverVersion string) string {
+	if !semver.Valid(serverVersion) {
+		return ""
+	}
+	for _, incompatibility := range incompatibilities {
+		if semver.Compare(serverVersion, incompatibility.below) < 0 {
+			return fmt.Sprintf("server version %s is older than %s: %s", serverVersion, incompatibility.below, incompatibility.reason)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package floomapi
+
+import "testing"
+
+func TestParseServerStatus(t *testing.T) {
+	tests := []struct {
+		body    string
+		status  string
+		version string
+	}{
+		{body: `{"status": "Healthy", "version": "1.2.0"}`, status: "Healthy", version: "1.2.0"},
+		{body: `{"status": "Healthy"}`, status: "Healthy"},
+		{body: `{"version": "1.2.0"}`, status: "OK", version: "1.2.0"},
+		{body: "Healthy", status: "Healthy"},
+		{body: "Healthy\n", status: "Healthy"},
+		{body: `"Healthy"`, status: "Healthy"},
+		{body: "", status: "OK"},
+		{body: "<html><body>up</body></html>", status: "OK"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.body, func(t *testing.T) {
+			status := parseServerStatus([]byte(test.body))
+			if status.Status != test.status || status.Version != test.version {
+				t.Errorf("parseServerStatus(%q) = %+v, want status %q and version %q", test.body, *status, test.status, test.version)
+			}
+		})
+	}
+}
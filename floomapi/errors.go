@@ -0,0 +1,222 @@
+package floomapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrorCategory groups API errors by what the user can do about them.
+type ErrorCategory string
+
+const (
+	CategoryAuth       ErrorCategory = "auth"
+	CategoryNotFound   ErrorCategory = "not found"
+	CategoryValidation ErrorCategory = "validation"
+	CategoryRateLimit  ErrorCategory = "rate limit"
+	CategoryServer     ErrorCategory = "server"
+	CategoryClient     ErrorCategory = "client"
+)
+
+// maxErrorBodySize limits how much of an error response is read.
+const maxErrorBodySize = 1 << 20
+
+// ValidationDetail is a single field-level validation error reported by the server.
+type ValidationDetail struct {
+	// Field is the path of the offending field as reported by the server, e.g. "pipeline.model[0].model".
+	Field   string
+	Message string
+}
+
+// Error is returned for every non-200 response of the Floom API.
+type Error struct {
+	Operation  string
+	StatusCode int
+	Status     string
+	Category   ErrorCategory
+	Message    string
+	Details    []ValidationDetail
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	var message strings.Builder
+	message.WriteString(e.Summary())
+	for _, detail := range e.Details {
+		if detail.Field != "" {
+			fmt.Fprintf(&message, "; %s: %s", detail.Field, detail.Message)
+		} else {
+			fmt.Fprintf(&message, "; %s", detail.Message)
+		}
+	}
+	return message.String()
+}
+
+// Summary describes the error without its validation details.
+func (e *Error) Summary() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "%s failed: %s (%s error)", e.Operation, e.Status, e.Category)
+	if e.Message != "" {
+		fmt.Fprintf(&message, ": %s", e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&message, " [request ID %s]", e.RequestID)
+	}
+	return message.String()
+}
+
+// categoryForStatus maps an HTTP status code to an error category.
+func categoryForStatus(status int) ErrorCategory {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CategoryAuth
+	case status == http.StatusNotFound:
+		return CategoryNotFound
+	case status == http.StatusBadRequest || status == http.StatusConflict || status == http.StatusUnprocessableEntity:
+		return CategoryValidation
+	case status == http.StatusTooManyRequests:
+		return CategoryRateLimit
+	case status >= 500:
+		return CategoryServer
+	default:
+		return CategoryClient
+	}
+}
+
+// errorPayload covers the error formats of the Floom API: a plain {"message": ...} object,
+// ASP.NET problem details with an "errors" map and "traceId", and lists of field errors.
+type errorPayload struct {
+	Message   string          `json:"message"`
+	Error     json.RawMessage `json:"error"`
+	Title     string          `json:"title"`
+	Detail    string          `json:"detail"`
+	Errors    json.RawMessage `json:"errors"`
+	TraceID   string          `json:"traceId"`
+	RequestID string          `json:"requestId"`
+}
+
+type fieldError struct {
+	Field        string `json:"field"`
+	PropertyName string `json:"propertyName"`
+	Message      string `json:"message"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// newError builds an Error from a non-200 response, consuming its body.
+func newError(operation string, resp *http.Response) *Error {
+	apiErr := &Error{
+		Operation:  operation,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Category:   categoryForStatus(resp.StatusCode),
+	}
+
+	for _, header := range []string{"X-Request-Id", "X-Correlation-Id", "Request-Id"} {
+		if value := resp.Header.Get(header); value != "" {
+			apiErr.RequestID = value
+			break
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return apiErr
+	}
+
+	var payload errorPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not JSON, keep the beginning of the body as the message.
+		apiErr.Message = truncate(string(body), 500)
+		return apiErr
+	}
+
+	apiErr.Message = firstNonEmpty(payload.Message, errorMessage(payload.Error), payload.Title, payload.Detail)
+	if payload.Detail != "" && payload.Detail != apiErr.Message {
+		apiErr.Message += ": " + payload.Detail
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = firstNonEmpty(payload.RequestID, payload.TraceID)
+	}
+	apiErr.Details = validationDetails(payload.Errors)
+
+	return apiErr
+}
+
+// validationDetails parses the "errors" member, either a map of field names to messages
+// or a list of field errors.
+func validationDetails(raw json.RawMessage) []ValidationDetail {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var details []ValidationDetail
+
+	var byField map[string][]string
+	if json.Unmarshal(raw, &byField) == nil {
+		fields := make([]string, 0, len(byField))
+		for field := range byField {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			for _, message := range byField[field] {
+				details = append(details, ValidationDetail{Field: field, Message: message})
+			}
+		}
+		return details
+	}
+
+	var list []fieldError
+	if json.Unmarshal(raw, &list) == nil {
+		for _, item := range list {
+			details = append(details, ValidationDetail{
+				Field:   firstNonEmpty(item.Field, item.PropertyName),
+				Message: firstNonEmpty(item.Message, item.ErrorMessage),
+			})
+		}
+		return details
+	}
+
+	var messages []string
+	if json.Unmarshal(raw, &messages) == nil {
+		for _, message := range messages {
+			details = append(details, ValidationDetail{Message: message})
+		}
+	}
+	return details
+}
+
+// errorMessage reads an "error" member given as a string or as {"message": ...}.
+func errorMessage(raw json.RawMessage) string {
+	var message string
+	if json.Unmarshal(raw, &message) == nil {
+		return message
+	}
+	var object struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		return object.Message
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length] + "..."
+}
@@ -0,0 +1,197 @@
+package floomapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// The HAR 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/. Only the
+// fields needed to replay and inspect API calls are written.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harRecorder collects traced exchanges as HAR entries.
+type harRecorder struct {
+	file harFile
+}
+
+func newHARRecorder(creatorVersion string) *harRecorder {
+	if creatorVersion == "" {
+		creatorVersion = "unknown"
+	}
+	return &harRecorder{file: harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "FloomCLI", Version: creatorVersion},
+		Entries: []harEntry{},
+	}}}
+}
+
+func (r *harRecorder) add(exchange *tracedExchange) {
+	req := exchange.request
+	entry := harEntry{
+		StartedDateTime: exchange.started.Format(time.RFC3339Nano),
+		Time:            milliseconds(exchange.wait + exchange.receive),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.Redacted(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Wait: milliseconds(exchange.wait), Receive: milliseconds(exchange.receive)},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	if body := exchange.requestBody; body != nil {
+		entry.Request.BodySize = body.total
+		contentType := req.Header.Get("Content-Type")
+		postData := &harPostData{MimeType: contentType}
+		if isTextContent(contentType) {
+			postData.Text = string(RedactSecrets(body.captured.Bytes()))
+		} else {
+			entry.Comment = "binary request body omitted"
+		}
+		entry.Request.PostData = postData
+	}
+
+	if exchange.err != nil {
+		entry.Response = harResponse{
+			StatusText:  exchange.err.Error(),
+			Headers:     []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		entry.Comment = "request failed: " + exchange.err.Error()
+	} else {
+		resp := exchange.response
+		contentType := resp.Header.Get("Content-Type")
+		entry.Response = harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     harHeaders(resp.Header),
+			Cookies:     []harNameValue{},
+			Content:     harContent{Size: exchange.responseSize, MimeType: contentType},
+			HeadersSize: -1,
+			BodySize:    exchange.responseSize,
+		}
+		if isTextContent(contentType) {
+			entry.Response.Content.Text = string(RedactSecrets(exchange.responseBody))
+		} else {
+			entry.Response.Content.Comment = "binary response body omitted"
+		}
+	}
+
+	r.file.Log.Entries = append(r.file.Log.Entries, entry)
+}
+
+// writeFile rewrites the HAR file with all entries so far, so the file is complete even
+// if the CLI exits early.
+func (r *harRecorder) writeFile(path string) error {
+	data, err := json.MarshalIndent(r.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func harHeaders(headers http.Header) []harNameValue {
+	redactedHeaders := RedactHeaders(headers)
+	names := make([]string, 0, len(redactedHeaders))
+	for name := range redactedHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := []harNameValue{}
+	for _, name := range names {
+		for _, value := range redactedHeaders[name] {
+			values = append(values, harNameValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
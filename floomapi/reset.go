@@ -0,0 +1,13 @@
+//go:build !plan9
+
+package floomapi
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnectionReset reports whether the server closed the connection during a request.
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,13 @@
+package floomapi
+
+import (
+	"errors"
+	"net"
+)
+
+// Plan 9 reports network errors as strings, so every failed read or write of an established
+// connection counts as a reset.
+func isConnectionReset(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "read" || opErr.Op == "write")
+}
@@ -0,0 +1,293 @@
+package floomapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Operation names identify the API endpoints in per-endpoint request settings.
+const (
+	OperationRegister = "register"
+	OperationUpload   = "upload"
+	OperationCommit   = "commit"
+	OperationVerify   = "verify"
+	OperationHealth   = "health"
+)
+
+// RequestSettings control the timeout and retries of requests to one endpoint.
+type RequestSettings struct {
+	// Timeout bounds a single attempt without progress: connecting, waiting for the response
+	// and reading it. While the request body is sent, every chunk restarts it, so uploads
+	// on slow links are not cut off as long as data flows.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// InitialBackoff is the upper bound of the first jittered backoff, doubled per retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff.
+	MaxBackoff time.Duration
+}
+
+// DefaultRequestSettings are used for every endpoint that has no settings of its own.
+var DefaultRequestSettings = RequestSettings{
+	Timeout:        DefaultTimeout,
+	MaxRetries:     3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// WithRequestSettings sets the request settings for all endpoints.
+func WithRequestSettings(settings RequestSettings) Option {
+	return func(c *Client) {
+		c.defaultSettings = settings
+	}
+}
+
+// WithEndpointSettings sets the request settings for a single operation, e.g. OperationUpload.
+func WithEndpointSettings(operation string, settings RequestSettings) Option {
+	return func(c *Client) {
+		c.endpointSettings[operation] = settings
+	}
+}
+
+// WithLogf makes the client report retries through logf.
+func WithLogf(logf func(format string, args ...interface{})) Option {
+	return func(c *Client) {
+		c.logf = logf
+	}
+}
+
+func (c *Client) settingsFor(operation string) RequestSettings {
+	if settings, ok := c.endpointSettings[operation]; ok {
+		return settings
+	}
+	return c.defaultSettings
+}
+
+// do sends the request created by newRequest, retrying transient failures with jittered
+// exponential backoff. newRequest is called for every attempt so the body can be re-sent.
+//
+// Requests that are not idempotent are only retried when the server cannot have acted on
+// them: the connection was never established, or the server answered 429 or 503. After an
+// ambiguous failure, such as a reset connection or a 502/504 from a gateway, they fail.
+func (c *Client) do(ctx context.Context, operation string, idempotent bool, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	settings := c.settingsFor(operation)
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		deadline := newAttemptDeadline(settings.Timeout, cancel)
+
+		req, err := newRequest(attemptCtx)
+		if err != nil {
+			deadline.stop()
+			cancel()
+			return nil, err
+		}
+		if req.Body != nil {
+			req.Body = &deadlineBody{ReadCloser: req.Body, deadline: deadline}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && !isRetryableStatus(resp.StatusCode, idempotent) {
+			// The attempt context must stay alive until the caller has read the body.
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel, deadline: deadline}
+			return resp, nil
+		}
+
+		if err != nil {
+			deadline.stop()
+			cancel()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			err = deadline.wrap(err)
+			if attempt >= settings.MaxRetries || !isRetryableError(err, idempotent) {
+				return nil, err
+			}
+		} else {
+			if attempt >= settings.MaxRetries {
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel, deadline: deadline}
+				return resp, nil
+			}
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			deadline.stop()
+			cancel()
+		}
+
+		wait := backoff(settings, attempt)
+		reason := fmt.Sprint(err)
+		if resp != nil {
+			reason = resp.Status
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+		}
+
+		if c.logf != nil {
+			c.logf("Retrying %s in %s (attempt %d of %d): %s", operation, wait.Round(time.Millisecond), attempt+2, settings.MaxRetries+1, reason)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableStatus reports whether a response status is a transient failure worth retrying.
+func isRetryableStatus(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// isRetryableError reports whether a transport error is transient. Errors that happen
+// before the connection is established are always safe to retry.
+func isRetryableError(err error, idempotent bool) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	if !idempotent {
+		return false
+	}
+
+	var netErr net.Error
+	return isConnectionReset(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// backoff returns a random wait between zero and the exponential backoff bound of attempt.
+func backoff(settings RequestSettings, attempt int) time.Duration {
+	bound := settings.InitialBackoff
+	for i := 0; i < attempt && bound < settings.MaxBackoff; i++ {
+		bound *= 2
+	}
+	if bound > settings.MaxBackoff {
+		bound = settings.MaxBackoff
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(bound)))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// attemptDeadline cancels an attempt that made no progress for the timeout. A zero timeout
+// never cancels it.
+type attemptDeadline struct {
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func newAttemptDeadline(timeout time.Duration, cancel context.CancelFunc) *attemptDeadline {
+	deadline := &attemptDeadline{timeout: timeout}
+	if timeout > 0 {
+		deadline.timer = time.AfterFunc(timeout, func() {
+			deadline.expired.Store(true)
+			cancel()
+		})
+	}
+	return deadline
+}
+
+// extend restarts the timeout, as the attempt made progress.
+func (d *attemptDeadline) extend() {
+	if d.timer != nil && !d.expired.Load() {
+		d.timer.Reset(d.timeout)
+	}
+}
+
+func (d *attemptDeadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// wrap reports an error caused by the expired deadline as context.DeadlineExceeded, like a
+// context timeout, instead of the cancellation it caused.
+func (d *attemptDeadline) wrap(err error) error {
+	if err == nil || !d.expired.Load() {
+		return err
+	}
+	return fmt.Errorf("no progress for %s: %w", d.timeout, context.DeadlineExceeded)
+}
+
+// deadlineBody extends the attempt deadline whenever the transport reads the request body.
+type deadlineBody struct {
+	io.ReadCloser
+	deadline *attemptDeadline
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.deadline.extend()
+	}
+	return n, err
+}
+
+// cancelOnClose releases the attempt context once the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel   context.CancelFunc
+	deadline *attemptDeadline
+}
+
+func (c *cancelOnClose) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err != io.EOF {
+		err = c.deadline.wrap(err)
+	}
+	return n, err
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.deadline.stop()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,43 @@
+//go:build !plan9
+
+package floomapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{name: "dial failure", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "temporary DNS failure", err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}, want: true},
+		{name: "unknown host", err: &net.DNSError{Err: "no such host", IsNotFound: true}, idempotent: true, want: false},
+		{name: "reset, idempotent", err: fmt.Errorf("Post: %w", reset), idempotent: true, want: true},
+		{name: "reset, not idempotent", err: fmt.Errorf("Post: %w", reset), want: false},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, idempotent: true, want: true},
+		{name: "deadline", err: context.DeadlineExceeded, idempotent: true, want: true},
+		{name: "canceled", err: context.Canceled, idempotent: true, want: false},
+		{name: "other error", err: errors.New("invalid URL"), idempotent: true, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryableError(test.err, test.idempotent); got != test.want {
+				t.Errorf("isRetryableError(%v, %v) = %v, want %v", test.err, test.idempotent, got, test.want)
+			}
+		})
+	}
+}
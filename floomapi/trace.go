@@ -0,0 +1,232 @@
+package floomapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLoggedBody is how much of a body is printed in the verbose log.
+	maxLoggedBody = 2 << 10
+	// maxCapturedBody is how much of a body is kept for the HAR file.
+	maxCapturedBody = 1 << 20
+	// redacted replaces secrets in logs, HAR files and cassettes.
+	redacted = "REDACTED"
+)
+
+// secretHeaders are never written to logs, HAR files or cassettes.
+var secretHeaders = map[string]bool{
+	"Api-Key":             true,
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretFieldPattern matches API key fields in YAML and JSON bodies, e.g. 'apiKey: sk-...'
+// or '"api_key": "..."'. The first group keeps the field name and separator.
+var secretFieldPattern = regexp.MustCompile(`(?i)("?(?:api[_-]?key|password|secret|token)"?\s*[:=]\s*"?)([^"\s,}]+)`)
+
+// RedactSecrets replaces the values of API key and similar fields in a body.
+func RedactSecrets(body []byte) []byte {
+	return secretFieldPattern.ReplaceAll(body, []byte("${1}"+redacted))
+}
+
+// RedactHeaders returns a copy of headers with secret values replaced.
+func RedactHeaders(headers http.Header) http.Header {
+	copied := headers.Clone()
+	for name := range copied {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			copied[name] = []string{redacted}
+		}
+	}
+	return copied
+}
+
+// Tracer logs every request and response with secrets redacted and optionally records
+// them into a HAR file. A single tracer is shared by all clients of a CLI run.
+type Tracer struct {
+	mu      sync.Mutex
+	log     io.Writer
+	har     *harRecorder
+	harPath string
+}
+
+// NewTracer creates a tracer logging to log, which may be nil, and writing a HAR file to
+// harPath, which may be empty. creatorVersion identifies the CLI in the HAR file.
+func NewTracer(log io.Writer, harPath, creatorVersion string) *Tracer {
+	tracer := &Tracer{log: log, harPath: harPath}
+	if harPath != "" {
+		tracer.har = newHARRecorder(creatorVersion)
+	}
+	return tracer
+}
+
+// Wrap returns a round tripper tracing every request sent through next.
+func (t *Tracer) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{next: next, tracer: t}
+}
+
+type tracingTransport struct {
+	next   http.RoundTripper
+	tracer *Tracer
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody *capturingReader
+	if req.Body != nil {
+		requestBody = &capturingReader{ReadCloser: req.Body, limit: maxCapturedBody}
+		req = req.Clone(req.Context())
+		req.Body = requestBody
+	}
+
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	waited := time.Since(started)
+
+	var responseBody []byte
+	var responseSize int64
+	if err == nil {
+		// Read the beginning of the response so it can be logged, then hand the full body on
+		captured, _ := io.ReadAll(io.LimitReader(resp.Body, maxCapturedBody))
+		responseBody, responseSize = captured, int64(len(captured))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(captured), resp.Body), resp.Body}
+	}
+	received := time.Since(started) - waited
+
+	exchange := &tracedExchange{
+		request:      req,
+		requestBody:  requestBody,
+		response:     resp,
+		responseBody: responseBody,
+		responseSize: responseSize,
+		err:          err,
+		started:      started,
+		wait:         waited,
+		receive:      received,
+	}
+	t.tracer.record(exchange)
+
+	return resp, err
+}
+
+// tracedExchange is a request and its response or error.
+type tracedExchange struct {
+	request      *http.Request
+	requestBody  *capturingReader
+	response     *http.Response
+	responseBody []byte
+	responseSize int64
+	err          error
+	started      time.Time
+	wait         time.Duration
+	receive      time.Duration
+}
+
+func (t *Tracer) record(exchange *tracedExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.log != nil {
+		t.logExchange(exchange)
+	}
+
+	if t.har != nil {
+		t.har.add(exchange)
+		if err := t.har.writeFile(t.harPath); err != nil && t.log != nil {
+			fmt.Fprintf(t.log, "Error writing HAR file: %v\n", err)
+		}
+	}
+}
+
+func (t *Tracer) logExchange(exchange *tracedExchange) {
+	req := exchange.request
+	fmt.Fprintf(t.log, "--> %s %s\n", req.Method, req.URL.Redacted())
+	logHeaders(t.log, req.Header)
+	if exchange.requestBody != nil {
+		logBody(t.log, req.Header.Get("Content-Type"), exchange.requestBody.captured.Bytes(), exchange.requestBody.total)
+	}
+
+	if exchange.err != nil {
+		fmt.Fprintf(t.log, "<-- error after %s: %v\n", exchange.wait.Round(time.Millisecond), exchange.err)
+		return
+	}
+
+	resp := exchange.response
+	fmt.Fprintf(t.log, "<-- %s (%s)\n", resp.Status, (exchange.wait + exchange.receive).Round(time.Millisecond))
+	logHeaders(t.log, resp.Header)
+	logBody(t.log, resp.Header.Get("Content-Type"), exchange.responseBody, exchange.responseSize)
+}
+
+func logHeaders(log io.Writer, headers http.Header) {
+	redactedHeaders := RedactHeaders(headers)
+	names := make([]string, 0, len(redactedHeaders))
+	for name := range redactedHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(log, "    %s: %s\n", name, strings.Join(redactedHeaders[name], ", "))
+	}
+}
+
+func logBody(log io.Writer, contentType string, body []byte, size int64) {
+	if size == 0 {
+		return
+	}
+	if !isTextContent(contentType) {
+		fmt.Fprintf(log, "    [%d bytes of %s]\n", size, contentType)
+		return
+	}
+
+	truncated := size > int64(len(body))
+	if len(body) > maxLoggedBody {
+		body, truncated = body[:maxLoggedBody], true
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(RedactSecrets(body)), "\n"), "\n") {
+		fmt.Fprintf(log, "    %s\n", line)
+	}
+	if truncated {
+		fmt.Fprintf(log, "    [truncated, %d bytes total]\n", size)
+	}
+}
+
+// isTextContent reports whether a body of the content type can be printed.
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "yaml") ||
+		strings.Contains(contentType, "xml")
+}
+
+// capturingReader keeps the first limit bytes read from a body and counts the rest.
+type capturingReader struct {
+	io.ReadCloser
+	captured bytes.Buffer
+	limit    int
+	total    int64
+}
+
+func (r *capturingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.total += int64(n)
+	if room := r.limit - r.captured.Len(); room > 0 {
+		if room > n {
+			room = n
+		}
+		r.captured.Write(p[:room])
+	}
+	return n, err
+}
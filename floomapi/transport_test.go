@@ -0,0 +1,19 @@
+package floomapi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWithTransportCopiesClient(t *testing.T) {
+	shared := &http.Client{}
+	transport := &http.Transport{}
+
+	client := NewClient("http://localhost:4050", Credentials{}, WithHTTPClient(shared), WithTransport(transport))
+	if client.httpClient.Transport != transport {
+		t.Errorf("the client does not use the transport")
+	}
+	if shared.Transport != nil {
+		t.Errorf("WithTransport changed the client given with WithHTTPClient")
+	}
+}
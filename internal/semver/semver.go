@@ -0,0 +1,102 @@
+// Package semver orders the semantic versions of CLI releases and Floom servers.
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compare compares two semantic versions such as "v1.2.0" and "1.3.0-beta.1", returning
+// -1, 0 or 1. Pre-releases sort before their release. Versions that cannot be parsed, such
+// as "dev", sort before all others.
+func Compare(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range va.numbers {
+		if va.numbers[i] != vb.numbers[i] {
+			return compareInts(va.numbers[i], vb.numbers[i])
+		}
+	}
+	return comparePrerelease(va.prerelease, vb.prerelease)
+}
+
+// Valid reports whether s is a version Compare can order, such as "v1.2.0".
+func Valid(s string) bool {
+	_, ok := parseVersion(s)
+	return ok
+}
+
+type version struct {
+	numbers    [3]int
+	prerelease string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	// Build metadata, and descriptions such as "1.2.0 (linux)", are ignored
+	if i := strings.IndexAny(s, "+ "); i >= 0 {
+		s = s[:i]
+	}
+	s, v.prerelease, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return v, false
+		}
+		v.numbers[i] = number
+	}
+	return v, true
+}
+
+// comparePrerelease compares pre-release identifiers, numerically where both are numbers.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numberA, errA := strconv.Atoi(partsA[i])
+		numberB, errB := strconv.Atoi(partsB[i])
+		var result int
+		if errA == nil && errB == nil {
+			result = compareInts(numberA, numberB)
+		} else {
+			result = strings.Compare(partsA[i], partsB[i])
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return compareInts(len(partsA), len(partsB))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package semver
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "1.2.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.0+build.5", "1.2.0", 0},
+		{"1.2.0", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.3.0-beta.1", "1.3.0", -1},
+		{"1.3.0-beta.2", "1.3.0-beta.10", -1},
+		{"1.3.0-alpha", "1.3.0-beta", -1},
+		{"dev", "0.0.1", -1},
+		{"1.0.0", "dev", 1},
+	}
+
+	for _, test := range tests {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"v1.2.0", true},
+		{"1.2", true},
+		{"1.3.0-beta.1+build.5", true},
+		{"1.2.0 (linux)", true},
+		{"dev", false},
+		{"1.2.3.4", false},
+		{"1.-2.0", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := Valid(test.version); got != test.want {
+			t.Errorf("Valid(%q) = %v, want %v", test.version, got, test.want)
+		}
+	}
+}
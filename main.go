@@ -5,14 +5,11 @@ package main
 
 import (
 	"FloomCLI/cmd"
-	"FloomCLI/config"
-	"log"
 )
 
 func main() {
-	err := config.InitConfig()
-	if err != nil {
-		log.Fatalf("Failed to load floom configuration file: %v", err)
-	}
+	cmd.SetBuildInfo(Version, Commit, BuildDate)
+	cmd.SetUpdateIndexURL(UpdateIndexURL)
+	cmd.SetUpdatePublicKey(UpdatePublicKey)
 	cmd.Execute()
 }
@@ -0,0 +1,562 @@
+// Package mockserver is an in-memory fake of the Floom API. It implements the endpoints
+// used by the CLI, so commands can be tried and tested without the docker-compose stack.
+package mockserver
+
+import (
+	"FloomCLI/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxUploadSize limits the size of an uploaded asset.
+const maxUploadSize = 64 << 20
+
+// DefaultVersion is the server version reported by the health endpoint unless Version is
+// changed.
+const DefaultVersion = "1.0.0"
+
+// pipelineNamePattern matches valid pipeline names, which become part of the pipeline URL.
+var pipelineNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// User is a registered user.
+type User struct {
+	ApiKey   string `json:"apiKey"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+}
+
+// Asset is an uploaded file.
+type Asset struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Uploaded    time.Time `json:"uploaded"`
+	Data        []byte    `json:"-"`
+}
+
+// Pipeline is a committed pipeline.
+type Pipeline struct {
+	Name      string             `json:"name"`
+	Committed time.Time          `json:"committed"`
+	Owner     string             `json:"owner,omitempty"`
+	YAML      string             `json:"yaml"`
+	Dto       models.PipelineDto `json:"-"`
+}
+
+// Server is the fake Floom API. The zero value is not usable, create it with New.
+type Server struct {
+	// RequireAuth rejects requests whose API key does not belong to a registered user.
+	// Otherwise any key, or none, is accepted, like a local Floom installation.
+	RequireAuth bool
+	// Logf, if set, is called for every request.
+	Logf func(format string, args ...interface{})
+	// Version is reported by the health endpoint, e.g. to test version checks.
+	Version string
+
+	mu        sync.Mutex
+	users     map[string]*User
+	assets    map[string]*Asset
+	pipelines map[string]*Pipeline
+	nextID    int
+
+	listener   net.Listener
+	httpServer *http.Server
+	mux        *http.ServeMux
+}
+
+// New creates an empty fake server.
+func New() *Server {
+	s := &Server{
+		users:     map[string]*User{},
+		assets:    map[string]*Asset{},
+		pipelines: map[string]*Pipeline{},
+		mux:       http.NewServeMux(),
+		Version:   DefaultVersion,
+	}
+
+	s.mux.HandleFunc("/v1/Misc/Health", s.handleHealth)
+	s.mux.HandleFunc("/v1/Users/Register", s.handleRegister)
+	s.mux.HandleFunc("/v1/Assets", s.handleAssets)
+	s.mux.HandleFunc("/v1/Assets/", s.handleAsset)
+	s.mux.HandleFunc("/v1/Pipelines", s.handlePipelines)
+	s.mux.HandleFunc("/v1/Pipelines/", s.handlePipeline)
+	s.mux.HandleFunc("/v1/Pipelines/Commit", s.handleCommit)
+	s.mux.HandleFunc("/", s.handleInvoke)
+	return s
+}
+
+// Start listens on addr and serves in the background. Use "127.0.0.1:0" to pick a free
+// port, then URL to find it.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+
+	go s.httpServer.Serve(listener)
+	return nil
+}
+
+// URL returns the base URL of a started server, e.g. "http://127.0.0.1:4050".
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close stops a started server, waiting up to ctx for requests in flight.
+func (s *Server) Close(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// ServeHTTP makes the server usable with httptest.NewServer.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Logf != nil {
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		s.mux.ServeHTTP(recorder, r)
+		s.Logf("%s %s -> %d (%s)", r.Method, r.URL.Path, recorder.status, time.Since(started).Round(time.Millisecond))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Assets returns the uploaded assets, oldest first.
+func (s *Server) Assets() []Asset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assets := make([]Asset, 0, len(s.assets))
+	for _, asset := range s.assets {
+		assets = append(assets, *asset)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Uploaded.Before(assets[j].Uploaded) })
+	return assets
+}
+
+// Pipelines returns the committed pipelines sorted by name.
+func (s *Server) Pipelines() []Pipeline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pipelines := make([]Pipeline, 0, len(s.pipelines))
+	for _, pipeline := range s.pipelines {
+		pipelines = append(pipelines, *pipeline)
+	}
+	sort.Slice(pipelines, func(i, j int) bool { return pipelines[i].Name < pipelines[j].Name })
+	return pipelines
+}
+
+// Pipeline returns a committed pipeline by name.
+func (s *Server) Pipeline(name string) (Pipeline, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pipeline, ok := s.pipelines[name]
+	if !ok {
+		return Pipeline{}, false
+	}
+	return *pipeline, true
+}
+
+// Users returns the registered users.
+func (s *Server) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "Healthy", "version": s.Version})
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	user := &User{
+		ApiKey:   randomToken(),
+		Username: fmt.Sprintf("user%d", s.nextID),
+		Nickname: fmt.Sprintf("Mock User %d", s.nextID),
+	}
+	s.users[user.ApiKey] = user
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) handleAssets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if _, ok := s.authenticate(w, r); ok {
+			writeJSON(w, http.StatusOK, s.Assets())
+		}
+	case http.MethodPost:
+		s.handleUpload(w, r)
+	default:
+		allowMethod(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid upload", map[string][]string{"file": {err.Error()}})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid upload", map[string][]string{"file": {err.Error()}})
+		return
+	}
+	hash := sha256.Sum256(data)
+
+	s.mu.Lock()
+	s.nextID++
+	asset := &Asset{
+		ID:          fmt.Sprintf("asset-%d", s.nextID),
+		Name:        header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(hash[:]),
+		Uploaded:    time.Now(),
+		Data:        data,
+	}
+	s.assets[asset.ID] = asset
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"fileId": asset.ID})
+}
+
+// handleAsset serves the content of an uploaded asset.
+func (s *Server) handleAsset(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+
+	s.mu.Lock()
+	asset, ok := s.assets[strings.TrimPrefix(r.URL.Path, "/v1/Assets/")]
+	s.mu.Unlock()
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "Asset not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.ContentType)
+	w.Write(asset.Data)
+}
+
+func (s *Server) handlePipelines(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if _, ok := s.authenticate(w, r); ok {
+		writeJSON(w, http.StatusOK, s.Pipelines())
+	}
+}
+
+// handlePipeline serves the committed YAML of a pipeline.
+func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+
+	pipeline, ok := s.Pipeline(strings.TrimPrefix(r.URL.Path, "/v1/Pipelines/"))
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "Pipeline not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/yaml")
+	io.WriteString(w, pipeline.YAML)
+}
+
+func (s *Server) handleCommit(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxUploadSize))
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid pipeline", nil)
+		return
+	}
+
+	var dto models.PipelineDto
+	if err := yaml.Unmarshal(body, &dto); err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid pipeline", map[string][]string{"": {err.Error()}})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if errs := s.validate(&dto); len(errs) > 0 {
+		writeProblem(w, http.StatusBadRequest, "One or more validation errors occurred.", errs)
+		return
+	}
+
+	pipeline := &Pipeline{Name: dto.Pipeline.Name, Committed: time.Now(), YAML: string(body), Dto: dto}
+	if user != nil {
+		pipeline.Owner = user.Username
+	}
+	s.pipelines[pipeline.Name] = pipeline
+
+	writeJSON(w, http.StatusOK, map[string]string{"name": pipeline.Name})
+}
+
+// validate checks a committed pipeline the way the Floom server does: required fields,
+// plugin packages and references to uploaded assets. Called with mu held.
+func (s *Server) validate(dto *models.PipelineDto) map[string][]string {
+	errs := map[string][]string{}
+	add := func(field, message string) {
+		errs[field] = append(errs[field], message)
+	}
+
+	if dto.Kind == "" {
+		add("kind", "The kind field is required.")
+	}
+
+	name := dto.Pipeline.Name
+	switch {
+	case name == "":
+		add("pipeline.name", "The name field is required.")
+	case !pipelineNamePattern.MatchString(name):
+		add("pipeline.name", "The name may only contain letters, digits, '-' and '_'.")
+	}
+
+	if len(dto.Pipeline.Model) == 0 {
+		add("pipeline.model", "At least one model is required.")
+	}
+
+	checkPlugins := func(field string, plugins []models.PluginConfigurationDto) {
+		for i, plugin := range plugins {
+			s.validatePlugin(fmt.Sprintf("%s[%d]", field, i), plugin, add)
+		}
+	}
+	checkPlugins("pipeline.model", dto.Pipeline.Model)
+	checkPlugins("pipeline.global", dto.Pipeline.Global)
+	if prompt := dto.Pipeline.Prompt; prompt != nil {
+		if prompt.Template != nil {
+			s.validatePlugin("pipeline.prompt.template", *prompt.Template, add)
+		}
+		checkPlugins("pipeline.prompt.context", prompt.Context)
+		checkPlugins("pipeline.prompt.optimization", prompt.Optimization)
+		checkPlugins("pipeline.prompt.validation", prompt.Validation)
+	}
+	if response := dto.Pipeline.Response; response != nil {
+		checkPlugins("pipeline.response.format", response.Format)
+		checkPlugins("pipeline.response.validation", response.Validation)
+	}
+
+	return errs
+}
+
+func (s *Server) validatePlugin(field string, plugin models.PluginConfigurationDto, add func(field, message string)) {
+	if plugin.Package == "" {
+		add(field+".package", "The package field is required.")
+	}
+	if _, ok := plugin.Configuration["path"]; ok {
+		add(field+".path", "Local paths must be uploaded as assets before committing.")
+	}
+
+	var assetIDs []interface{}
+	switch value := plugin.Configuration["assetId"].(type) {
+	case nil:
+	case []interface{}:
+		assetIDs = value
+	default:
+		assetIDs = []interface{}{value}
+	}
+	for i, assetID := range assetIDs {
+		id, _ := assetID.(string)
+		if _, ok := s.assets[id]; !ok {
+			add(fmt.Sprintf("%s.assetId[%d]", field, i), fmt.Sprintf("Asset '%v' does not exist.", assetID))
+		}
+	}
+}
+
+// invokeRequest invokes a pipeline. The fake server echoes the prompt instead of calling a
+// model.
+type invokeRequest struct {
+	Prompt    string            `json:"prompt"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+type invokeResponse struct {
+	Pipeline  string            `json:"pipeline"`
+	Value     string            `json:"value"`
+	Variables map[string]string `json:"variables,omitempty"`
+	ProcessID string            `json:"processId"`
+}
+
+// handleInvoke serves pipeline invocations. Like Floom cloud, where a pipeline is invoked
+// with a POST to https://<name>-<username>.pipeline.floom.ai/, the pipeline is taken from
+// the first label of the Host header. Pipelines without an owner are also found by name.
+func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeProblem(w, http.StatusNotFound, "Not found", nil)
+		return
+	}
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+
+	pipeline, ok := s.pipelineForHost(r.Host)
+	if !ok {
+		writeProblem(w, http.StatusNotFound, fmt.Sprintf("No pipeline is served at '%s'", r.Host), nil)
+		return
+	}
+
+	var request invokeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxUploadSize)).Decode(&request); err != nil {
+		writeProblem(w, http.StatusBadRequest, "Invalid request", map[string][]string{"": {err.Error()}})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invokeResponse{
+		Pipeline:  pipeline.Name,
+		Value:     request.Prompt,
+		Variables: request.Variables,
+		ProcessID: randomToken(),
+	})
+}
+
+// pipelineForHost finds the pipeline served at host, e.g. "docs-alice.pipeline.floom.ai".
+func (s *Server) pipelineForHost(host string) (Pipeline, bool) {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	label, _, _ := strings.Cut(host, ".")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pipeline := range s.pipelines {
+		if label == pipeline.Name+"-"+pipeline.Owner || (pipeline.Owner == "" && label == pipeline.Name) {
+			return *pipeline, true
+		}
+	}
+	return Pipeline{}, false
+}
+
+// authenticate looks up the user of the request's API key. Without RequireAuth, unknown
+// keys are accepted and the user is nil.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	apiKey := r.Header.Get("Api-Key")
+
+	s.mu.Lock()
+	user := s.users[apiKey]
+	s.mu.Unlock()
+
+	if user == nil && s.RequireAuth {
+		writeProblem(w, http.StatusUnauthorized, "Invalid API key", nil)
+		return nil, false
+	}
+	return user, true
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeProblem(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeProblem writes an error in the ASP.NET problem details format of the Floom API.
+func writeProblem(w http.ResponseWriter, status int, title string, errs map[string][]string) {
+	problem := map[string]interface{}{
+		"title":   title,
+		"status":  status,
+		"traceId": randomToken()[:16],
+	}
+	if len(errs) > 0 {
+		problem["errors"] = errs
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+func randomToken() string {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		panic(errors.New("mockserver: no randomness available"))
+	}
+	return hex.EncodeToString(token)
+}
+
+// statusRecorder remembers the status code written by a handler for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
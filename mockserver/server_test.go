@@ -0,0 +1,104 @@
+package mockserver
+
+import (
+	"FloomCLI/floomapi"
+	"FloomCLI/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeployAndInvoke(t *testing.T) {
+	server := New()
+	server.RequireAuth = true
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	ctx := context.Background()
+
+	registration, err := floomapi.NewClient(httpServer.URL, floomapi.Credentials{}).Register(ctx)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	client := floomapi.NewClient(httpServer.URL, floomapi.Credentials{ApiKey: registration.ApiKey})
+
+	file := filepath.Join(t.TempDir(), "manual.txt")
+	if err := os.WriteFile(file, []byte("How to deploy a pipeline"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assetID, err := client.UploadAsset(ctx, file, nil)
+	if err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+	if assets := server.Assets(); len(assets) != 1 || assets[0].ID != assetID || assets[0].Name != "manual.txt" {
+		t.Fatalf("server assets %+v, want manual.txt as %s", assets, assetID)
+	}
+
+	pipeline := func(assetID string) models.PipelineDto {
+		return models.PipelineDto{
+			Kind: "floom/pipeline/1.2",
+			Pipeline: models.PipelineDetailsDto{
+				Name:  "docs",
+				Model: []models.PluginConfigurationDto{{Package: "floom/model/connector/openai", Configuration: map[string]interface{}{"model": "gpt-4"}}},
+				Prompt: &models.PromptStageDto{
+					Context: []models.PluginConfigurationDto{{Package: "floom/prompt/context/pdf", Configuration: map[string]interface{}{"assetId": assetID}}},
+				},
+			},
+		}
+	}
+	if err := client.CommitPipeline(ctx, pipeline("asset-missing")); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("commit referencing a missing asset: %v, want a validation error", err)
+	}
+	if err := client.CommitPipeline(ctx, pipeline(assetID)); err != nil {
+		t.Fatalf("CommitPipeline failed: %v", err)
+	}
+	committed, ok := server.Pipeline("docs")
+	if !ok || committed.Owner != registration.Username {
+		t.Fatalf("committed pipeline %+v, want docs owned by %s", committed, registration.Username)
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "invoke", host: "docs-" + registration.Username + ".pipeline.floom.ai", apiKey: registration.ApiKey, wantStatus: http.StatusOK},
+		{name: "other owner", host: "docs-someone.pipeline.floom.ai", apiKey: registration.ApiKey, wantStatus: http.StatusNotFound},
+		{name: "unknown API key", host: "docs-" + registration.Username + ".pipeline.floom.ai", apiKey: "unknown", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, httpServer.URL+"/", strings.NewReader(`{"prompt": "How do I deploy?"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Host = test.host
+			req.Header.Set("Api-Key", test.apiKey)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("invoke returned %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+			var response invokeResponse
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Pipeline != "docs" || response.Value != "How do I deploy?" {
+				t.Errorf("invoke response %+v, want the prompt echoed by docs", response)
+			}
+		})
+	}
+}
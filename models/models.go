@@ -1,9 +1,14 @@
 package models
 
+import "sort"
+
 // PipelineDto represents the structure of a pipeline.
+// Fields that this CLI does not know about are kept in Extra at every level and sent
+// to the server unchanged, so pipelines written for a newer server are not corrupted.
 type PipelineDto struct {
-	Kind     string             `yaml:"kind"`
-	Pipeline PipelineDetailsDto `yaml:"pipeline"`
+	Kind     string                 `yaml:"kind"`
+	Pipeline PipelineDetailsDto     `yaml:"pipeline"`
+	Extra    map[string]interface{} `yaml:",inline"`
 }
 
 type PipelineDetailsDto struct {
@@ -12,6 +17,7 @@ type PipelineDetailsDto struct {
 	Prompt   *PromptStageDto          `yaml:"prompt,omitempty"`
 	Response *ResponseStageDto        `yaml:"response,omitempty"`
 	Global   []PluginConfigurationDto `yaml:"global,omitempty"`
+	Extra    map[string]interface{}   `yaml:",inline"`
 }
 
 type PromptStageDto struct {
@@ -19,11 +25,36 @@ type PromptStageDto struct {
 	Context      []PluginConfigurationDto `yaml:"context,omitempty"`
 	Optimization []PluginConfigurationDto `yaml:"optimization,omitempty"`
 	Validation   []PluginConfigurationDto `yaml:"validation,omitempty"`
+	Extra        map[string]interface{}   `yaml:",inline"`
 }
 
 type ResponseStageDto struct {
 	Format     []PluginConfigurationDto `yaml:"format,omitempty"`
 	Validation []PluginConfigurationDto `yaml:"validation,omitempty"`
+	Extra      map[string]interface{}   `yaml:",inline"`
+}
+
+// UnknownFields returns the dotted paths of all fields that are passed through
+// without being understood by this CLI version, sorted alphabetically.
+func (p *PipelineDto) UnknownFields() []string {
+	var fields []string
+	collect := func(prefix string, extra map[string]interface{}) {
+		for key := range extra {
+			fields = append(fields, prefix+key)
+		}
+	}
+
+	collect("", p.Extra)
+	collect("pipeline.", p.Pipeline.Extra)
+	if p.Pipeline.Prompt != nil {
+		collect("pipeline.prompt.", p.Pipeline.Prompt.Extra)
+	}
+	if p.Pipeline.Response != nil {
+		collect("pipeline.response.", p.Pipeline.Response.Extra)
+	}
+
+	sort.Strings(fields)
+	return fields
 }
 
 type PluginConfigurationDto struct {
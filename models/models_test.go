@@ -0,0 +1,91 @@
+package models
+
+import (
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestPipelineDtoRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		unknown []string
+	}{
+		{
+			name: "known fields only",
+			input: `kind: floom/pipeline/1.2
+pipeline:
+  name: docs
+  model:
+    - package: floom/model/connector/openai
+      model: gpt-4
+  prompt:
+    template:
+      package: floom/prompt/template/default
+      text: Hi
+  response:
+    format:
+      - package: floom/response/json
+`,
+		},
+		{
+			name: "unknown fields at every level",
+			input: `kind: floom/pipeline/1.3
+metadata:
+  labels: {team: docs}
+pipeline:
+  name: docs
+  description: Answers questions about the manual
+  model:
+    - package: floom/model/connector/openai
+      model: gpt-4
+  prompt:
+    template:
+      package: floom/prompt/template/default
+      text: Hi
+    examples:
+      - {input: a, output: b}
+  response:
+    format:
+      - package: floom/response/json
+    streaming: true
+  routing:
+    strategy: fallback
+`,
+			unknown: []string{"metadata", "pipeline.description", "pipeline.prompt.examples", "pipeline.response.streaming", "pipeline.routing"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var pipeline PipelineDto
+			if err := yaml.Unmarshal([]byte(test.input), &pipeline); err != nil {
+				t.Fatalf("failed to decode pipeline: %v", err)
+			}
+
+			if unknown := pipeline.UnknownFields(); !reflect.DeepEqual(unknown, test.unknown) {
+				t.Errorf("UnknownFields = %v, want %v", unknown, test.unknown)
+			}
+			if pipeline.Pipeline.Name != "docs" || pipeline.Pipeline.Prompt == nil || pipeline.Pipeline.Prompt.Template.Package != "floom/prompt/template/default" {
+				t.Errorf("known fields were not decoded: %+v", pipeline.Pipeline)
+			}
+
+			// Encoding keeps every field, known or not
+			data, err := yaml.Marshal(pipeline)
+			if err != nil {
+				t.Fatalf("failed to encode pipeline: %v", err)
+			}
+			var want, got map[string]interface{}
+			if err := yaml.Unmarshal([]byte(test.input), &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip =\n%s\nwant\n%s", data, test.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+)
+
+// Replace atomically replaces the binary at executable with newBinary, which must be in
+// the same directory. The old binary is kept as executable + ".old" until verify accepts
+// the new one; if verify fails, the old binary is restored.
+//
+// The running binary is renamed rather than overwritten, which also works on Windows,
+// where a running executable cannot be deleted. There the ".old" file is left behind and
+// removed by the next update.
+func Replace(executable, newBinary string, verify func(path string) error) error {
+	backup := executable + ".old"
+	os.Remove(backup)
+
+	if err := os.Rename(executable, backup); err != nil {
+		return fmt.Errorf("failed to move the current binary aside: %w", err)
+	}
+	if err := os.Rename(newBinary, executable); err != nil {
+		if rollbackErr := os.Rename(backup, executable); rollbackErr != nil {
+			return fmt.Errorf("failed to install the new binary: %v; restoring the previous binary from %s failed too: %w", err, backup, rollbackErr)
+		}
+		return fmt.Errorf("failed to install the new binary: %w", err)
+	}
+
+	if verify != nil {
+		if err := verify(executable); err != nil {
+			return rollback(executable, backup, fmt.Errorf("the new binary failed its check: %w", err))
+		}
+	}
+
+	// Fails for a running binary on Windows, which is fine
+	os.Remove(backup)
+	return nil
+}
+
+// rollback restores the previous binary after a failed update.
+func rollback(executable, backup string, cause error) error {
+	if err := os.Rename(backup, executable); err != nil {
+		return fmt.Errorf("%v; restoring the previous binary from %s failed: %w", cause, backup, err)
+	}
+	return fmt.Errorf("%w, the previous binary was restored", cause)
+}
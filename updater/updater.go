@@ -0,0 +1,225 @@
+// Package updater finds, downloads and verifies releases of the Floom CLI and replaces the
+// running binary with them.
+package updater
+
+import (
+	"FloomCLI/internal/semver"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultChannel is the channel of stable releases.
+const DefaultChannel = "stable"
+
+// maxBinarySize limits the size of a downloaded binary.
+const maxBinarySize = 256 << 20
+
+// Index is the release index. It lists the releases of all channels.
+type Index struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release is a released version of the CLI. Assets holds its binaries by platform, e.g.
+// "linux/amd64".
+type Release struct {
+	Version string           `json:"version"`
+	Channel string           `json:"channel"`
+	Date    string           `json:"date,omitempty"`
+	Notes   string           `json:"notes,omitempty"`
+	Assets  map[string]Asset `json:"assets"`
+}
+
+// Asset is the binary of a release for one platform. URL may be relative to the index.
+// SHA256 is the hex checksum of the binary and Signature the base64 ed25519 signature of
+// the binary, made with the release signing key.
+type Asset struct {
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// Updater downloads releases from a release index and verifies them with the public key
+// of the release signing key.
+type Updater struct {
+	IndexURL   string
+	PublicKey  ed25519.PublicKey
+	HTTPClient *http.Client
+}
+
+// Platform returns the platform of the running binary, e.g. "linux/amd64".
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// ParsePublicKey parses a base64 ed25519 public key.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key, expected %d base64 encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func (u *Updater) httpClient() *http.Client {
+	if u.HTTPClient != nil {
+		return u.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (u *Updater) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// FetchIndex downloads the release index.
+func (u *Updater) FetchIndex(ctx context.Context) (*Index, error) {
+	resp, err := u.get(ctx, u.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var index Index
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode release index %s: %w", u.IndexURL, err)
+	}
+	return &index, nil
+}
+
+// Select returns the release with the given version, or the latest release of channel if
+// version is empty.
+func (index *Index) Select(channel, version string) (*Release, error) {
+	if version != "" {
+		for i, release := range index.Releases {
+			if semver.Compare(release.Version, version) == 0 {
+				return &index.Releases[i], nil
+			}
+		}
+		return nil, fmt.Errorf("version %s is not in the release index", version)
+	}
+
+	var latest *Release
+	for i, release := range index.Releases {
+		if release.Channel != channel {
+			continue
+		}
+		if latest == nil || semver.Compare(release.Version, latest.Version) > 0 {
+			latest = &index.Releases[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no releases in channel '%s'", channel)
+	}
+	return latest, nil
+}
+
+// Download downloads the binary of a release for this platform into a temporary file in
+// dir and verifies its checksum and signature. The caller removes the file.
+func (u *Updater) Download(ctx context.Context, release *Release, dir string) (string, error) {
+	if len(u.PublicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("no release signing key, downloads cannot be verified")
+	}
+	asset, ok := release.Assets[Platform()]
+	if !ok {
+		return "", fmt.Errorf("release %s has no binary for %s", release.Version, Platform())
+	}
+	checksum, err := hex.DecodeString(asset.SHA256)
+	if err != nil || len(checksum) != sha256.Size {
+		return "", fmt.Errorf("release %s has an invalid checksum for %s", release.Version, Platform())
+	}
+	signature, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return "", fmt.Errorf("release %s has an invalid signature for %s", release.Version, Platform())
+	}
+
+	assetURL, err := u.resolve(asset.URL)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.get(ctx, assetURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", release.Version, err)
+	}
+	defer resp.Body.Close()
+
+	binary, err := io.ReadAll(io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", release.Version, err)
+	}
+	if len(binary) > maxBinarySize {
+		return "", fmt.Errorf("the binary of %s is larger than %d bytes", release.Version, maxBinarySize)
+	}
+
+	// Both checks must pass: the checksum catches damaged downloads, the signature catches
+	// a tampered index or binary
+	if sum := sha256.Sum256(binary); !bytes.Equal(sum[:], checksum) {
+		return "", fmt.Errorf("checksum mismatch for %s, the download is damaged", assetURL)
+	}
+	if !ed25519.Verify(u.PublicKey, binary, signature) {
+		return "", fmt.Errorf("invalid signature for %s, the binary was not signed with the release key", assetURL)
+	}
+
+	file, err := os.CreateTemp(dir, ".floom-update-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(binary)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// resolve resolves an asset URL relative to the index URL.
+func (u *Updater) resolve(assetURL string) (string, error) {
+	base, err := url.Parse(u.IndexURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid index URL: %w", err)
+	}
+	ref, err := url.Parse(assetURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset URL '%s': %w", assetURL, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Executable returns the path of the running binary, with symlinks resolved.
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
@@ -0,0 +1,178 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	index := &Index{Releases: []Release{
+		{Version: "v1.0.0", Channel: "stable"},
+		{Version: "v1.2.0", Channel: "stable"},
+		{Version: "v1.1.0", Channel: "stable"},
+		{Version: "v1.3.0-beta.1", Channel: "beta"},
+	}}
+
+	tests := []struct {
+		name    string
+		channel string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "latest stable", channel: "stable", want: "v1.2.0"},
+		{name: "latest beta", channel: "beta", want: "v1.3.0-beta.1"},
+		{name: "pinned version", channel: "stable", version: "1.0.0", want: "v1.0.0"},
+		{name: "pinned version of another channel", channel: "stable", version: "v1.3.0-beta.1", want: "v1.3.0-beta.1"},
+		{name: "unknown version", channel: "stable", version: "v9.0.0", wantErr: true},
+		{name: "empty channel", channel: "nightly", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			release, err := index.Select(test.channel, test.version)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Select error = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && release.Version != test.want {
+				t.Errorf("Select = %s, want %s", release.Version, test.want)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := []byte("#!/bin/sh\necho floom\n")
+	checksum := sha256.Sum256(binary)
+	validAsset := Asset{
+		URL:       "floom-v1.1.0",
+		SHA256:    hex.EncodeToString(checksum[:]),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, binary)),
+	}
+
+	tests := []struct {
+		name      string
+		asset     Asset
+		served    []byte
+		publicKey ed25519.PublicKey
+		wantErr   string
+	}{
+		{name: "valid", asset: validAsset, served: binary, publicKey: publicKey},
+		{name: "damaged download", asset: validAsset, served: append([]byte("x"), binary...), publicKey: publicKey, wantErr: "checksum mismatch"},
+		{name: "other signing key", asset: validAsset, served: binary, publicKey: otherPublicKey, wantErr: "invalid signature"},
+		{name: "no signing key", asset: validAsset, served: binary, wantErr: "no release signing key"},
+		{
+			name:      "tampered binary with matching checksum",
+			asset:     Asset{URL: validAsset.URL, SHA256: hexChecksum([]byte("evil")), Signature: validAsset.Signature},
+			served:    []byte("evil"),
+			publicKey: publicKey,
+			wantErr:   "invalid signature",
+		},
+		{name: "missing asset", asset: Asset{URL: "missing", SHA256: validAsset.SHA256, Signature: validAsset.Signature}, served: binary, publicKey: publicKey, wantErr: "404"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := Index{Releases: []Release{{Version: "v1.1.0", Channel: DefaultChannel, Assets: map[string]Asset{Platform(): test.asset}}}}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/releases/index.json":
+					json.NewEncoder(w).Encode(index)
+				case "/releases/floom-v1.1.0":
+					w.Write(test.served)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			u := &Updater{IndexURL: server.URL + "/releases/index.json", PublicKey: test.publicKey}
+			fetched, err := u.FetchIndex(context.Background())
+			if err != nil {
+				t.Fatalf("FetchIndex failed: %v", err)
+			}
+			release, err := fetched.Select(DefaultChannel, "")
+			if err != nil {
+				t.Fatalf("Select failed: %v", err)
+			}
+
+			dir := t.TempDir()
+			path, err := u.Download(context.Background(), release, dir)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Download error = %v, want %q", err, test.wantErr)
+				}
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("a failed download left %d files behind", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Download failed: %v", err)
+			}
+			if downloaded, _ := os.ReadFile(path); string(downloaded) != string(binary) {
+				t.Errorf("downloaded %q, want %q", downloaded, binary)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name      string
+		verifyErr error
+		want      string
+	}{
+		{name: "accepted", want: "new"},
+		{name: "rolled back", verifyErr: errors.New("wrong version"), want: "old"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			executable, newBinary := filepath.Join(dir, "floom"), filepath.Join(dir, ".floom-update-1")
+			if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newBinary, []byte("new"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Replace(executable, newBinary, func(string) error { return test.verifyErr })
+			if (err != nil) != (test.verifyErr != nil) {
+				t.Fatalf("Replace error = %v, want error %v", err, test.verifyErr)
+			}
+			if installed, _ := os.ReadFile(executable); string(installed) != test.want {
+				t.Errorf("installed binary is %q, want %q", installed, test.want)
+			}
+			if _, err := os.Stat(executable + ".old"); !os.IsNotExist(err) {
+				t.Errorf("the backup of the previous binary was left behind")
+			}
+		})
+	}
+}
+
+func hexChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,22 +2,62 @@ package utils
 
 import (
 	"FloomCLI/models"
+	"bytes"
+	"errors"
 	"gopkg.in/yaml.v3"
-	"os"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-func ParseYaml(yamlFile string) (*models.PipelineDto, error) {
-	file, err := os.ReadFile(yamlFile)
-	if err != nil {
-		return nil, err
+// ParseYaml streams the '---' separated YAML documents from r and calls handle with the
+// root node of each non-empty document, in order. It stops at the first error.
+func ParseYaml(r io.Reader, handle func(root *yaml.Node) error) error {
+	decoder := yaml.NewDecoder(r)
+
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		// Skip empty documents, e.g. a leading or doubled '---'
+		if len(document.Content) == 0 || document.Content[0].Tag == "!!null" {
+			continue
+		}
+		if err := handle(document.Content[0]); err != nil {
+			return err
+		}
 	}
+}
 
-	var config models.PipelineDto
-	if err := yaml.Unmarshal(file, &config); err != nil {
+// DecodePipeline decodes a pipeline document root node into a PipelineDto.
+func DecodePipeline(root *yaml.Node) (*models.PipelineDto, error) {
+	var pipeline models.PipelineDto
+	if err := root.Decode(&pipeline); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return &pipeline, nil
+}
+
+// SerializeYamlNode encodes a YAML node using the canonical two-space indentation.
+func SerializeYamlNode(node *yaml.Node) (string, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
 }
 
 func SerializeYaml(pipeline models.PipelineDto) (string, error) {
@@ -28,3 +68,238 @@ func SerializeYaml(pipeline models.PipelineDto) (string, error) {
 
 	return string(out), nil
 }
+
+// Canonical key order for the known levels of a pipeline document. Keys that
+// are not listed keep their original relative order after the known ones.
+var (
+	documentKeyOrder = []string{"kind", "extends", "pipeline"}
+	pipelineKeyOrder = []string{"name", "model", "prompt", "response", "global"}
+	promptKeyOrder   = []string{"template", "context", "optimization", "validation"}
+	responseKeyOrder = []string{"format", "validation"}
+	pluginKeyOrder   = []string{"package"}
+)
+
+// FormatYaml rewrites one or more pipeline YAML documents into their canonical form:
+// stages in canonical order, 'package' first inside every plugin block, plain
+// scalars unless quoting is required, block style collections, two-space
+// indentation and single-string context paths normalized into lists.
+// Comments are preserved.
+func FormatYaml(data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if len(document.Content) > 0 {
+			formatDocument(document.Content[0])
+		}
+		normalizeStyle(&document)
+
+		if err := encoder.Encode(&document); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func formatDocument(root *yaml.Node) {
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+	sortMappingKeys(root, documentKeyOrder)
+
+	pipeline := mappingValue(root, "pipeline")
+	if pipeline == nil || pipeline.Kind != yaml.MappingNode {
+		return
+	}
+	sortMappingKeys(pipeline, pipelineKeyOrder)
+
+	formatPluginList(mappingValue(pipeline, "model"))
+	formatPluginList(mappingValue(pipeline, "global"))
+
+	if prompt := mappingValue(pipeline, "prompt"); prompt != nil && prompt.Kind == yaml.MappingNode {
+		sortMappingKeys(prompt, promptKeyOrder)
+		formatPlugin(mappingValue(prompt, "template"))
+		formatPluginList(mappingValue(prompt, "optimization"))
+		formatPluginList(mappingValue(prompt, "validation"))
+
+		if context := mappingValue(prompt, "context"); context != nil && context.Kind == yaml.SequenceNode {
+			for _, plugin := range context.Content {
+				formatPlugin(plugin)
+				normalizePathList(plugin)
+			}
+		}
+	}
+
+	if response := mappingValue(pipeline, "response"); response != nil && response.Kind == yaml.MappingNode {
+		sortMappingKeys(response, responseKeyOrder)
+		formatPluginList(mappingValue(response, "format"))
+		formatPluginList(mappingValue(response, "validation"))
+	}
+}
+
+func formatPluginList(list *yaml.Node) {
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, plugin := range list.Content {
+		formatPlugin(plugin)
+	}
+}
+
+func formatPlugin(plugin *yaml.Node) {
+	if plugin == nil || plugin.Kind != yaml.MappingNode {
+		return
+	}
+	sortMappingKeys(plugin, pluginKeyOrder)
+}
+
+// normalizePathList turns 'path: file.pdf' into a single element list.
+func normalizePathList(plugin *yaml.Node) {
+	path := mappingValue(plugin, "path")
+	if path == nil || path.Kind != yaml.ScalarNode {
+		return
+	}
+
+	item := *path
+	item.HeadComment, item.LineComment, item.FootComment = "", "", ""
+	*path = yaml.Node{
+		Kind:        yaml.SequenceNode,
+		Tag:         "!!seq",
+		Content:     []*yaml.Node{&item},
+		HeadComment: path.HeadComment,
+		LineComment: path.LineComment,
+		FootComment: path.FootComment,
+	}
+}
+
+// mappingValue returns the value node stored under key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sortMappingKeys reorders the key/value pairs of a mapping so that the keys in order
+// come first, followed by all other keys in their original order.
+func sortMappingKeys(mapping *yaml.Node, order []string) {
+	rank := func(key string) int {
+		for i, known := range order {
+			if key == known {
+				return i
+			}
+		}
+		return len(order)
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return rank(pairs[i][0].Value) < rank(pairs[j][0].Value)
+	})
+
+	mapping.Content = mapping.Content[:0]
+	for _, pair := range pairs {
+		mapping.Content = append(mapping.Content, pair[0], pair[1])
+	}
+}
+
+// normalizeStyle drops explicit quoting and flow style so the encoder picks the
+// canonical representation. Literal and folded blocks are kept as written.
+func normalizeStyle(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		node.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+		// YAML 1.1 parsers read e.g. a plain 'yes' as a boolean, so such strings stay quoted
+		if node.Tag == "!!str" && isYaml11Bool(node.Value) {
+			node.Style |= yaml.DoubleQuotedStyle
+		}
+	}
+	node.Style &^= yaml.FlowStyle
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// Line comments on collections are emitted after the following key once the
+			// collection switches to block style, so keep them on the owning key instead.
+			if value.Kind != yaml.ScalarNode && value.LineComment != "" && key.LineComment == "" {
+				key.LineComment, value.LineComment = value.LineComment, ""
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		normalizeStyle(child)
+	}
+}
+
+// isYaml11Bool reports whether a plain scalar is a boolean in YAML 1.1, but a string in
+// YAML 1.2, which the encoder follows.
+func isYaml11Bool(value string) bool {
+	switch value {
+	case "y", "Y", "yes", "Yes", "YES", "n", "N", "no", "No", "NO", "on", "On", "ON", "off", "Off", "OFF":
+		return true
+	}
+	return false
+}
+
+// LocateField finds the node for a field path reported by the server, such as
+// "pipeline.model[0].model", "Pipeline.Model.0.Model" or "$.pipeline.name". Keys are
+// matched case-insensitively. If the full path does not exist, the deepest existing
+// node on the path is returned with exact set to false.
+func LocateField(root *yaml.Node, field string) (node *yaml.Node, exact bool) {
+	field = strings.TrimPrefix(strings.TrimPrefix(field, "$"), ".")
+	field = strings.NewReplacer("[", ".", "]", "").Replace(field)
+
+	node = root
+	for _, segment := range strings.Split(field, ".") {
+		if segment == "" {
+			continue
+		}
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if strings.EqualFold(node.Content[i].Value, segment) {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+
+		if next == nil {
+			return node, false
+		}
+		node = next
+	}
+
+	return node, true
+}
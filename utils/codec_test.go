@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatYaml(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "stages in canonical order",
+			input: `pipeline:
+  response:
+    format:
+      - package: floom/response/json
+  name: docs
+  prompt:
+    template:
+      text: Hi
+      package: floom/prompt/template/default
+  model:
+    - model: gpt-4
+      package: floom/model/connector/openai
+kind: floom/pipeline/1.2
+`,
+			expected: `kind: floom/pipeline/1.2
+pipeline:
+  name: docs
+  model:
+    - package: floom/model/connector/openai
+      model: gpt-4
+  prompt:
+    template:
+      package: floom/prompt/template/default
+      text: Hi
+  response:
+    format:
+      - package: floom/response/json
+`,
+		},
+		{
+			name: "comments are kept",
+			input: `# Support bot
+kind: floom/pipeline/1.2
+pipeline:
+  # Shown in the dashboard
+  name: support
+  model: # the fallback comes second
+    - package: floom/model/connector/openai
+      model: gpt-4 # cheapest that works
+`,
+			expected: `# Support bot
+kind: floom/pipeline/1.2
+pipeline:
+  # Shown in the dashboard
+  name: support
+  model: # the fallback comes second
+    - package: floom/model/connector/openai
+      model: gpt-4 # cheapest that works
+`,
+		},
+		{
+			name: "single context path becomes a list",
+			input: `pipeline:
+  prompt:
+    context:
+      - package: floom/prompt/context/pdf
+        path: manual.pdf # the latest edition
+      - package: floom/prompt/context/pdf
+        path: [a.pdf, b.pdf]
+`,
+			expected: `pipeline:
+  prompt:
+    context:
+      - package: floom/prompt/context/pdf
+        path: # the latest edition
+          - manual.pdf
+      - package: floom/prompt/context/pdf
+        path:
+          - a.pdf
+          - b.pdf
+`,
+		},
+		{
+			name: "quoting and flow style are dropped",
+			input: `kind: "floom/pipeline/1.2"
+pipeline: {name: 'docs', model: [{package: floom/model/connector/openai}]}
+`,
+			expected: `kind: floom/pipeline/1.2
+pipeline:
+  name: docs
+  model:
+    - package: floom/model/connector/openai
+`,
+		},
+		{
+			name: "quotes that are required are kept",
+			input: `pipeline:
+  name: "yes"
+  description: 'on'
+  prompt:
+    template:
+      text: "a: b"
+`,
+			expected: `pipeline:
+  name: "yes"
+  prompt:
+    template:
+      text: 'a: b'
+  description: "on"
+`,
+		},
+		{
+			name: "every document is formatted",
+			input: `pipeline: {name: a}
+---
+pipeline: {name: b}
+`,
+			expected: `pipeline:
+  name: a
+---
+pipeline:
+  name: b
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := FormatYaml([]byte(test.input))
+			if err != nil {
+				t.Fatalf("FormatYaml failed: %v", err)
+			}
+			if string(formatted) != test.expected {
+				t.Errorf("FormatYaml =\n%s\nwant\n%s", formatted, test.expected)
+			}
+
+			// Formatting formatted output changes nothing
+			again, err := FormatYaml(formatted)
+			if err != nil {
+				t.Fatalf("FormatYaml of formatted output failed: %v", err)
+			}
+			if string(again) != string(formatted) {
+				t.Errorf("FormatYaml is not idempotent, second run =\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatYamlInvalid(t *testing.T) {
+	_, err := FormatYaml([]byte("pipeline: [unclosed"))
+	if err == nil || !strings.Contains(err.Error(), "yaml") {
+		t.Fatalf("FormatYaml error = %v, want a YAML error", err)
+	}
+}
@@ -0,0 +1,373 @@
+package utils
+
+import (
+	"FloomCLI/models"
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pipeline composition keys.
+//
+// A pipeline can declare 'extends: ./base.yml' at the top level of the document. The
+// extending document is deep-merged on top of the base document:
+//   - mappings are merged key by key, a value of '$delete' removes the key from the base
+//   - plugin lists are merged item by item, items are matched on 'package' unless the
+//     item names another key with '$match: <key>', an item with '$delete: true' removes
+//     the matching base item, unmatched items are appended
+//   - everything else replaces the base value
+//
+// Overlays use the same semantics. When deploying pipeline.yml to a target such as
+// 'cloud', pipeline.cloud.yml is merged on top if it exists next to the pipeline file.
+// For files with several documents, overlay documents are matched on 'pipeline.name'.
+const (
+	extendsKey      = "extends"
+	matchKey        = "$match"
+	deleteKey       = "$delete"
+	defaultMatchKey = "package"
+)
+
+// PipelineDocument is a single pipeline read from a file or from standard input, after
+// 'extends' and the target overlay have been merged.
+type PipelineDocument struct {
+	// Source is the file name the document was read from, or "-" for standard input.
+	Source string
+	// Index is the position of the document within its source, starting at 0.
+	Index int
+	// BaseDir is the directory relative file references are resolved against.
+	BaseDir string
+	// Node is the merged document in canonical order, with comments.
+	Node *yaml.Node
+	// Pipeline is the decoded merged document.
+	Pipeline *models.PipelineDto
+}
+
+// ResolvePath resolves a file reference found in the document against its BaseDir.
+func (d *PipelineDocument) ResolvePath(path string) string {
+	if filepath.IsAbs(path) || d.BaseDir == "" {
+		return path
+	}
+	return filepath.Join(d.BaseDir, path)
+}
+
+// LoadPipelineFile reads every pipeline document in a file, resolves their 'extends'
+// chains and merges the overlay file for the given deployment target, if any. Relative
+// file references are resolved against baseDir, or the working directory if baseDir is
+// empty. 'extends' paths are always relative to the file that declares them.
+func LoadPipelineFile(yamlFile, baseDir, target string) ([]*PipelineDocument, error) {
+	file, err := os.Open(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var overlays []*yaml.Node
+	if overlayFile := OverlayPath(yamlFile, target); overlayFile != "" {
+		if _, statErr := os.Stat(overlayFile); statErr == nil {
+			overlays, err = loadOverlays(overlayFile)
+			if err != nil {
+				return nil, fmt.Errorf("error loading overlay %s: %w", overlayFile, err)
+			}
+		}
+	}
+
+	return loadPipelines(file, yamlFile, filepath.Dir(yamlFile), baseDir, overlays)
+}
+
+// LoadPipelineStream reads every pipeline document from r, e.g. standard input, and
+// resolves their 'extends' chains against baseDir. Streams have no target overlays.
+func LoadPipelineStream(r io.Reader, source, baseDir string) ([]*PipelineDocument, error) {
+	return loadPipelines(r, source, baseDir, baseDir, nil)
+}
+
+// loadPipelines reads the documents of r and merges each on top of the document it
+// extends, relative to extendsDir, and below the overlays that apply to it. The overlays
+// are copied for every document, so merging never changes them.
+func loadPipelines(r io.Reader, source, extendsDir, baseDir string, overlays []*yaml.Node) ([]*PipelineDocument, error) {
+	var documents []*PipelineDocument
+
+	err := ParseYaml(r, func(root *yaml.Node) error {
+		index := len(documents)
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("document %d does not contain a YAML mapping", index+1)
+		}
+
+		merged, err := resolveExtends(root, extendsDir, map[string]bool{})
+		if err != nil {
+			return fmt.Errorf("document %d: %w", index+1, err)
+		}
+
+		name := pipelineName(merged)
+		for _, overlay := range overlays {
+			if overlayName := pipelineName(overlay); overlayName == "" || overlayName == name {
+				merged = mergeNodes(merged, copyNode(overlay))
+			}
+		}
+
+		stripMergeMarkers(merged)
+		formatDocument(merged)
+
+		pipeline, err := DecodePipeline(merged)
+		if err != nil {
+			return fmt.Errorf("document %d: error decoding pipeline: %w", index+1, err)
+		}
+
+		documents = append(documents, &PipelineDocument{
+			Source:   source,
+			Index:    index,
+			BaseDir:  baseDir,
+			Node:     merged,
+			Pipeline: pipeline,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", source, err)
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%s does not contain any pipeline", source)
+	}
+
+	return documents, nil
+}
+
+// OverlayPath returns the overlay file name for a pipeline file and a deployment target,
+// e.g. pipeline.cloud.yml for pipeline.yml and 'cloud'. Custom endpoint targets have no
+// overlay and yield an empty string.
+func OverlayPath(yamlFile, target string) string {
+	if yamlFile == "-" || target == "" || strings.ContainsAny(target, `:/\.`) {
+		return ""
+	}
+
+	ext := filepath.Ext(yamlFile)
+	return strings.TrimSuffix(yamlFile, ext) + "." + target + ext
+}
+
+// loadOverlays reads the documents of an overlay file. An overlay document applies to
+// the pipeline with the same 'pipeline.name', or to every pipeline if it has no name.
+func loadOverlays(overlayFile string) ([]*yaml.Node, error) {
+	file, err := os.Open(overlayFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var overlays []*yaml.Node
+	err = ParseYaml(file, func(root *yaml.Node) error {
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("overlay document %d does not contain a YAML mapping", len(overlays)+1)
+		}
+		merged, err := resolveExtends(root, filepath.Dir(overlayFile), map[string]bool{})
+		if err != nil {
+			return err
+		}
+		overlays = append(overlays, merged)
+		return nil
+	})
+
+	return overlays, err
+}
+
+// loadBaseFile loads the document a pipeline extends, itself resolving 'extends'.
+func loadBaseFile(yamlFile string, seen map[string]bool) (*yaml.Node, error) {
+	absPath, err := filepath.Abs(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	if seen[absPath] {
+		return nil, fmt.Errorf("circular extends detected at %s", yamlFile)
+	}
+	seen[absPath] = true
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", yamlFile, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s does not contain a YAML mapping", yamlFile)
+	}
+
+	return resolveExtends(document.Content[0], filepath.Dir(absPath), seen)
+}
+
+// resolveExtends merges root on top of the document it extends, if any. Relative base
+// paths are resolved against baseDir. seen guards against 'extends' cycles.
+func resolveExtends(root *yaml.Node, baseDir string, seen map[string]bool) (*yaml.Node, error) {
+	extends := mappingValue(root, extendsKey)
+	if extends == nil {
+		return root, nil
+	}
+	if extends.Kind != yaml.ScalarNode || extends.Value == "" {
+		return nil, fmt.Errorf("'extends' must be a file path")
+	}
+	deleteMappingKey(root, extendsKey)
+
+	basePath := extends.Value
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(baseDir, basePath)
+	}
+
+	base, err := loadBaseFile(basePath, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeNodes(base, root), nil
+}
+
+// copyNode returns a deep copy of node, which mergeNodes may then modify or reference.
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	copied := *node
+	if node.Content != nil {
+		copied.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			copied.Content[i] = copyNode(child)
+		}
+	}
+	copied.Alias = copyNode(node.Alias)
+	return &copied
+}
+
+func pipelineName(root *yaml.Node) string {
+	if name := mappingValue(mappingValue(root, "pipeline"), "name"); name != nil {
+		return name.Value
+	}
+	return ""
+}
+
+// mergeNodes deep-merges override on top of base and returns the result. Both nodes
+// may be modified.
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base == nil {
+		return override
+	}
+
+	switch {
+	case base.Kind == yaml.MappingNode && override.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key, value := override.Content[i], override.Content[i+1]
+
+			if isDeleteMarker(value) {
+				deleteMappingKey(base, key.Value)
+				continue
+			}
+
+			if index := mappingIndex(base, key.Value); index >= 0 {
+				base.Content[index+1] = mergeNodes(base.Content[index+1], value)
+			} else {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+
+	case base.Kind == yaml.SequenceNode && override.Kind == yaml.SequenceNode && isPluginList(override):
+		for _, item := range override.Content {
+			index := matchingItemIndex(base, item)
+			deleted := mappingValue(item, deleteKey)
+
+			switch {
+			case deleted != nil && deleted.Value == "true":
+				if index >= 0 {
+					base.Content = append(base.Content[:index], base.Content[index+1:]...)
+				}
+			case index >= 0:
+				base.Content[index] = mergeNodes(base.Content[index], item)
+			default:
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+
+	default:
+		return override
+	}
+}
+
+// matchingItemIndex returns the index of the base list item matching item on its match
+// key, or -1.
+func matchingItemIndex(list, item *yaml.Node) int {
+	key := defaultMatchKey
+	if match := mappingValue(item, matchKey); match != nil {
+		key = match.Value
+	}
+
+	value := mappingValue(item, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return -1
+	}
+
+	for i, candidate := range list.Content {
+		if other := mappingValue(candidate, key); other != nil && other.Kind == yaml.ScalarNode && other.Value == value.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+func isPluginList(list *yaml.Node) bool {
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return len(list.Content) > 0
+}
+
+func isDeleteMarker(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == deleteKey
+}
+
+// stripMergeMarkers removes '$match' and '$delete' keys and unmatched '$delete: true' items
+// from the result.
+func stripMergeMarkers(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		deleteMappingKey(node, matchKey)
+	case yaml.SequenceNode:
+		kept := node.Content[:0]
+		for _, item := range node.Content {
+			if deleted := mappingValue(item, deleteKey); deleted != nil && deleted.Value == "true" {
+				continue
+			}
+			deleteMappingKey(item, deleteKey)
+			kept = append(kept, item)
+		}
+		node.Content = kept
+	}
+
+	for _, child := range node.Content {
+		stripMergeMarkers(child)
+	}
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func deleteMappingKey(mapping *yaml.Node, key string) {
+	if index := mappingIndex(mapping, key); index >= 0 {
+		mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		override string
+		expected string
+	}{
+		{
+			name:     "mappings are merged key by key",
+			base:     "pipeline: {name: base, model: gpt-3}",
+			override: "pipeline: {model: gpt-4}",
+			expected: "pipeline: {name: base, model: gpt-4}",
+		},
+		{
+			name:     "delete marker removes a key",
+			base:     "pipeline: {name: base, model: gpt-3}",
+			override: "pipeline: {model: $delete}",
+			expected: "pipeline: {name: base}",
+		},
+		{
+			name:     "plugin items are matched on package",
+			base:     "plugins: [{package: a, key: 1}, {package: b, key: 2}]",
+			override: "plugins: [{package: b, key: 3}, {package: c}]",
+			expected: "plugins: [{package: a, key: 1}, {package: b, key: 3}, {package: c}]",
+		},
+		{
+			name:     "plugin items are matched on the $match key",
+			base:     "plugins: [{package: a, id: x, key: 1}, {package: a, id: y, key: 2}]",
+			override: "plugins: [{$match: id, id: y, key: 3}]",
+			expected: "plugins: [{package: a, id: x, key: 1}, {package: a, id: y, key: 3}]",
+		},
+		{
+			name:     "$delete true removes the matching item",
+			base:     "plugins: [{package: a}, {package: b}]",
+			override: "plugins: [{package: a, $delete: true}]",
+			expected: "plugins: [{package: b}]",
+		},
+		{
+			name:     "unmatched $delete true items are dropped",
+			base:     "plugins: [{package: a}]",
+			override: "plugins: [{package: c, $delete: true}]",
+			expected: "plugins: [{package: a}]",
+		},
+		{
+			name:     "$delete other than true keeps the item",
+			base:     "plugins: [{package: a, key: 1}]",
+			override: "plugins: [{package: a, key: 2, $delete: false}]",
+			expected: "plugins: [{package: a, key: 2}]",
+		},
+		{
+			name:     "scalar lists are replaced",
+			base:     "tags: [a, b]",
+			override: "tags: [c]",
+			expected: "tags: [c]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := mergeNodes(parseMapping(t, test.base), parseMapping(t, test.override))
+			stripMergeMarkers(merged)
+
+			if actual, expected := decodeNode(t, merged), decodeNode(t, parseMapping(t, test.expected)); !reflect.DeepEqual(actual, expected) {
+				t.Errorf("merged document is %v, expected %v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestLoadPipelinesCopiesOverlays(t *testing.T) {
+	overlay := parseMapping(t, "pipeline:\n  model:\n    - package: floom/model/connector/openai\n      model: gpt-4")
+	expected := decodeNode(t, overlay)
+
+	stream := "pipeline:\n  name: one\n---\npipeline:\n  name: two\n  model:\n    - package: floom/model/connector/openai\n      model: gpt-3\n"
+	documents, err := loadPipelines(strings.NewReader(stream), "-", "", "", []*yaml.Node{overlay})
+	if err != nil {
+		t.Fatalf("failed to load pipelines: %v", err)
+	}
+
+	for _, document := range documents {
+		if model := document.Pipeline.Pipeline.Model; len(model) != 1 || model[0].Configuration["model"] != "gpt-4" {
+			t.Errorf("document %d has model %v, expected the overlay's gpt-4", document.Index, model)
+		}
+	}
+	if actual := decodeNode(t, overlay); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("overlay changed to %v, expected %v", actual, expected)
+	}
+}
+
+func parseMapping(t *testing.T, document string) *yaml.Node {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(document), &node); err != nil {
+		t.Fatalf("invalid test document %q: %v", document, err)
+	}
+	return node.Content[0]
+}
+
+func decodeNode(t *testing.T, node *yaml.Node) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		t.Fatalf("failed to decode node: %v", err)
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/mattn/go-isatty"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth       = 24
+	progressRenderInterval = 100 * time.Millisecond
+)
+
+// ProgressGroup renders a progress bar per file and, for several files, a combined total
+// line. Finished bars are printed once above the live bars. Rendering is disabled when
+// stdout is not a terminal, so piped or scripted output stays clean.
+type ProgressGroup struct {
+	mu         sync.Mutex
+	out        io.Writer
+	enabled    bool
+	bars       []*ProgressBar
+	started    time.Time
+	lastRender time.Time
+	liveLines  int
+}
+
+// ProgressBar tracks the progress of a single file.
+type ProgressBar struct {
+	group    *ProgressGroup
+	name     string
+	total    int64
+	sent     int64
+	started  time.Time
+	finished time.Time
+	printed  bool
+}
+
+// NewProgressGroup creates a progress group drawing on stderr if stdout and stderr are
+// terminals.
+func NewProgressGroup() *ProgressGroup {
+	return &ProgressGroup{
+		out:     os.Stderr,
+		enabled: isTerminal(os.Stdout) && isTerminal(os.Stderr),
+		started: time.Now(),
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	return isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd())
+}
+
+// Add registers a file of the given size. It is counted in the total right away and drawn
+// once its upload starts.
+func (g *ProgressGroup) Add(name string, total int64) *ProgressBar {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bar := &ProgressBar{group: g, name: name, total: total}
+	g.bars = append(g.bars, bar)
+	return bar
+}
+
+// Update sets the number of bytes sent. It matches the floomapi.UploadProgress signature.
+func (b *ProgressBar) Update(sent, total int64) {
+	g := b.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if b.started.IsZero() || sent < b.sent {
+		// First update, or the upload was restarted by a retry
+		b.started = time.Now()
+	}
+	b.sent, b.total = sent, total
+
+	if time.Since(g.lastRender) >= progressRenderInterval {
+		g.render()
+	}
+}
+
+// Done marks the file as completely sent.
+func (b *ProgressBar) Done() {
+	g := b.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if b.started.IsZero() {
+		b.started = time.Now()
+	}
+	b.sent = b.total
+	b.finished = time.Now()
+	g.render()
+}
+
+// Finish draws the final state and leaves the cursor below the progress output.
+func (g *ProgressGroup) Finish() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.render()
+	g.liveLines = 0
+}
+
+// render redraws the live region: finished bars that were not printed yet are printed
+// permanently, followed by the active bars and the total line. Called with mu held.
+func (g *ProgressGroup) render() {
+	if !g.enabled {
+		return
+	}
+	g.lastRender = time.Now()
+
+	var out strings.Builder
+	if g.liveLines > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", g.liveLines)
+	}
+
+	var live []string
+	var sent, total int64
+	allFinished := time.Now()
+	for _, bar := range g.bars {
+		sent += bar.sent
+		total += bar.total
+		if bar.finished.IsZero() {
+			allFinished = time.Time{}
+		}
+
+		switch {
+		case !bar.finished.IsZero() && !bar.printed:
+			bar.printed = true
+			out.WriteString("\x1b[2K" + bar.line() + "\n")
+		case bar.finished.IsZero() && !bar.started.IsZero():
+			live = append(live, bar.line())
+		}
+	}
+
+	if len(g.bars) > 1 {
+		live = append(live, progressLine("Total", sent, total, g.started, allFinished))
+	}
+
+	for _, line := range live {
+		out.WriteString("\x1b[2K" + line + "\n")
+	}
+	// Clear lines left over from a previous, taller live region
+	for i := len(live); i < g.liveLines; i++ {
+		out.WriteString("\x1b[2K\n")
+	}
+	if extra := g.liveLines - len(live); extra > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", extra)
+	}
+	g.liveLines = len(live)
+
+	io.WriteString(g.out, out.String())
+}
+
+func (b *ProgressBar) line() string {
+	return progressLine(b.name, b.sent, b.total, b.started, b.finished)
+}
+
+// progressLine formats a single progress line with bytes sent, speed and ETA.
+func progressLine(name string, sent, total int64, started, finished time.Time) string {
+	end := time.Now()
+	if !finished.IsZero() {
+		end = finished
+	}
+
+	var fraction float64 = 1
+	if total > 0 {
+		fraction = float64(sent) / float64(total)
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	var speed float64
+	if elapsed := end.Sub(started).Seconds(); elapsed > 0 && !started.IsZero() {
+		speed = float64(sent) / elapsed
+	}
+
+	status := "done"
+	if finished.IsZero() {
+		status = "ETA --"
+		if speed > 0 {
+			remaining := time.Duration(float64(total-sent) / speed * float64(time.Second))
+			status = "ETA " + remaining.Round(time.Second).String()
+		}
+	}
+
+	if len(name) > 24 {
+		name = "..." + name[len(name)-21:]
+	}
+
+	return fmt.Sprintf("%-24s [%s] %9s / %-9s %10s/s  %s", name, bar, formatBytes(sent), formatBytes(total), formatBytes(int64(speed)), status)
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "12.3 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for value := n / unit; value >= unit; value /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Keys of the prompt.template plugin. 'file' references a template file relative to the
+// pipeline, 'system' holds the inline template text the server uses.
+const (
+	TemplateFileKey   = "file"
+	TemplateSystemKey = "system"
+)
+
+// Prompt templates use {{name}} placeholders. Names start with a letter or underscore and
+// may contain letters, digits, underscores, dots and dashes. Whitespace inside the braces
+// is allowed, so {{ name }} is the same placeholder as {{name}}.
+var placeholderNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// TemplatePlaceholder is a single {{placeholder}} occurrence in a prompt template.
+type TemplatePlaceholder struct {
+	Name  string
+	Line  int
+	start int
+	end   int
+}
+
+// ParseTemplate checks that every placeholder in content is well formed and returns all
+// placeholder occurrences in order. All malformed placeholders are reported together.
+func ParseTemplate(content string) ([]TemplatePlaceholder, error) {
+	var placeholders []TemplatePlaceholder
+	var problems []string
+
+	// Only text starting with '{{' is a placeholder, a lone '}}' is literal text, e.g. in JSON
+	for offset := 0; offset < len(content); {
+		opening := strings.Index(content[offset:], "{{")
+		if opening < 0 {
+			break
+		}
+
+		start := offset + opening
+		end := strings.Index(content[start+2:], "}}")
+		if end < 0 {
+			problems = append(problems, fmt.Sprintf("line %d: '{{' is never closed", lineAt(content, start)))
+			break
+		}
+		end += start + 2
+
+		inner := content[start+2 : end]
+		name := strings.TrimSpace(inner)
+		switch {
+		case strings.Contains(inner, "{{") || strings.Contains(inner, "\n"):
+			problems = append(problems, fmt.Sprintf("line %d: '{{' is never closed", lineAt(content, start)))
+		case name == "":
+			problems = append(problems, fmt.Sprintf("line %d: empty placeholder", lineAt(content, start)))
+		case !placeholderNamePattern.MatchString(name):
+			problems = append(problems, fmt.Sprintf("line %d: invalid placeholder name '%s'", lineAt(content, start), name))
+		default:
+			placeholders = append(placeholders, TemplatePlaceholder{Name: name, Line: lineAt(content, start), start: start, end: end + 2})
+		}
+
+		offset = end + 2
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("malformed template placeholders:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return placeholders, nil
+}
+
+// LoadTemplateFile reads a prompt template file and checks its placeholders.
+func LoadTemplateFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading template file: %w", err)
+	}
+
+	content := string(data)
+	if _, err := ParseTemplate(content); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	return content, nil
+}
+
+// TemplateContent returns the prompt template text of a pipeline document, read from the
+// referenced 'file' or taken from the inline 'system' text. ok is false if the pipeline
+// has no template text.
+func TemplateContent(document *PipelineDocument) (content string, ok bool, err error) {
+	prompt := document.Pipeline.Pipeline.Prompt
+	if prompt == nil || prompt.Template == nil {
+		return "", false, nil
+	}
+	configuration := prompt.Template.Configuration
+
+	if file, exists := configuration[TemplateFileKey]; exists {
+		path, isString := file.(string)
+		if !isString || path == "" {
+			return "", false, fmt.Errorf("prompt.template.%s must be a file path", TemplateFileKey)
+		}
+		content, err := LoadTemplateFile(document.ResolvePath(path))
+		return content, err == nil, err
+	}
+
+	if system, isString := configuration[TemplateSystemKey].(string); isString {
+		return system, true, nil
+	}
+
+	return "", false, nil
+}
+
+// RenderTemplate replaces every placeholder in content with its value from vars. It
+// fails if the template is malformed or if any placeholder has no value.
+func RenderTemplate(content string, vars map[string]string) (string, error) {
+	placeholders, err := ParseTemplate(content)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered strings.Builder
+	missing := map[string]bool{}
+	last := 0
+	for _, placeholder := range placeholders {
+		value, ok := vars[placeholder.Name]
+		if !ok {
+			missing[placeholder.Name] = true
+			continue
+		}
+		rendered.WriteString(content[last:placeholder.start])
+		rendered.WriteString(value)
+		last = placeholder.end
+	}
+	rendered.WriteString(content[last:])
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("no value for placeholders: %s", strings.Join(names, ", "))
+	}
+
+	return rendered.String(), nil
+}
+
+func lineAt(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
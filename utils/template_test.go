@@ -0,0 +1,36 @@
+package utils
+
+import "testing"
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		names   []string
+		wantErr bool
+	}{
+		{name: "placeholders", content: "Hi {{name}}, see {{ order.id }}", names: []string{"name", "order.id"}},
+		{name: "literal closing braces", content: `Reply with {"a": {"b": 1}}`},
+		{name: "placeholder in JSON", content: `{"user": {"name": "{{name}}"}}`, names: []string{"name"}},
+		{name: "unclosed placeholder", content: "Hi {{name", wantErr: true},
+		{name: "empty placeholder", content: "Hi {{ }}", wantErr: true},
+		{name: "invalid name", content: "Hi {{1st}}", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			placeholders, err := ParseTemplate(test.content)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseTemplate(%q) error = %v, want error %v", test.content, err, test.wantErr)
+			}
+			if len(placeholders) != len(test.names) {
+				t.Fatalf("ParseTemplate(%q) found %d placeholders, want %d", test.content, len(placeholders), len(test.names))
+			}
+			for i, placeholder := range placeholders {
+				if placeholder.Name != test.names[i] {
+					t.Errorf("placeholder %d is %q, want %q", i, placeholder.Name, test.names[i])
+				}
+			}
+		})
+	}
+}
@@ -1,3 +1,17 @@
 package main
 
-var Version = "v1.0.0"
+// Build information, set by build.sh with -ldflags "-X main.Version=...". Builds without
+// them report "dev", and the commit and date recorded by the Go toolchain, if any.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// UpdateIndexURL is the URL of the release index 'floom update' reads, set by build.sh from
+// INDEX_URL. Builds without it need --index-url or FLOOM_UPDATE_INDEX_URL to update.
+var UpdateIndexURL = ""
+
+// UpdatePublicKey is the base64 ed25519 public key of the release signing key, which
+// 'floom update' uses to verify downloads. build.sh derives it from the signing key.
+var UpdatePublicKey = ""